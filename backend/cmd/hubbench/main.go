@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"time"
+
+	"backend/internal/websocket"
+)
+
+// Fan-out benchmark for the WebSocket hub
+//
+//	go run ./cmd/hubbench -clients 100000 -broadcasts 20 -baseline
+func main() {
+	clients := flag.Int("clients", 100_000, "simulated connections")
+	shards := flag.Int("shards", 0, "hub shards (0 = one per CPU)")
+	broadcasts := flag.Int("broadcasts", 20, "version broadcasts to time")
+	baseline := flag.Bool("baseline", false, "also run with a single shard for comparison")
+	flag.Parse()
+
+	log.Printf("📊 Hub fan-out benchmark: %d simulated connections, %d CPUs", *clients, runtime.NumCPU())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	runs := []int{*shards}
+	if *baseline {
+		runs = append([]int{1}, runs...)
+	}
+
+	for _, n := range runs {
+		result, err := websocket.RunFanoutBenchmark(ctx, websocket.BenchmarkConfig{
+			Clients:    *clients,
+			Shards:     n,
+			Broadcasts: *broadcasts,
+		})
+		if err != nil {
+			log.Fatalf("Benchmark failed: %v", err)
+		}
+		report(result)
+		runtime.GC()
+	}
+}
+
+// report prints latency percentiles and throughput for one run
+func report(result *websocket.BenchmarkResult) {
+	latencies := append([]time.Duration(nil), result.Latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	mean := total / time.Duration(len(latencies))
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	fmt.Printf("\n=== %d shards ===\n", result.Shards)
+	fmt.Printf("clients:        %d (registered in %s)\n", result.Clients, result.Setup.Round(time.Millisecond))
+	fmt.Printf("broadcasts:     %d\n", len(latencies))
+	fmt.Printf("fan-out p50:    %s\n", percentile(latencies, 0.50))
+	fmt.Printf("fan-out p99:    %s\n", percentile(latencies, 0.99))
+	fmt.Printf("fan-out max:    %s\n", latencies[len(latencies)-1])
+	fmt.Printf("throughput:     %.0f messages/sec\n", float64(result.Clients)/mean.Seconds())
+	fmt.Printf("delivered:      %d (dropped %d, evicted %d)\n", result.Delivered, result.Dropped, result.Evictions)
+	fmt.Printf("heap in use:    %d MB\n", mem.HeapInuse/1024/1024)
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	index := int(float64(len(sorted)-1) * p)
+	return sorted[index].Round(time.Microsecond)
+}
@@ -4,14 +4,24 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"backend/internal/anticheat"
+	"backend/internal/audit"
 	"backend/internal/api/handlers"
+	"backend/internal/auth"
+	"backend/internal/breaker"
 	"backend/internal/config"
+	"backend/internal/graphqlapi"
+	"backend/internal/grpcapi"
+	"backend/internal/idempotency"
 	"backend/internal/jobs"
+	"backend/internal/models"
+	"backend/internal/ratelimit"
 	"backend/internal/repository"
 	"backend/internal/service"
 	"backend/internal/websocket"
@@ -23,6 +33,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	fiberws "github.com/gofiber/websocket/v2"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
@@ -49,9 +60,16 @@ func main() {
 	}
 	log.Println("✓ Connected to Redis")
 
-	// Initialize repositories
-	postgresRepo := repository.NewPostgresRepository(db)
-	redisRepo := repository.NewRedisRepository(redisClient)
+	// Initialize repositories, each guarded by a circuit breaker
+	breakerConfig := breaker.Config{
+		FailureThreshold: cfg.Breaker.FailureThreshold,
+		OpenTimeout:      time.Duration(cfg.Breaker.OpenTimeoutSec) * time.Second,
+		HalfOpenProbes:   cfg.Breaker.HalfOpenProbes,
+	}
+	postgresRepo := repository.NewPostgresRepository(db).
+		WithBreaker(repository.NewPostgresBreaker(breakerConfig))
+	redisRepo := repository.NewRedisRepository(redisClient).
+		WithBreaker(repository.NewRedisBreaker(breakerConfig))
 
 	// Run migrations
 	if err := postgresRepo.AutoMigrate(); err != nil {
@@ -59,20 +77,70 @@ func main() {
 	}
 	log.Println("✓ Database migrations completed")
 
+	// Append-only audit log of admin and key management actions
+	auditLog := audit.NewLog(postgresRepo)
+
+	// API keys: writes need a key with the write-scores scope, admin endpoints the admin scope
+	authenticator := auth.NewAuthenticator(postgresRepo, cfg.Auth.RequireReadKey)
+
+	// Player tokens let game clients submit and view data for their own username
+	jwtVerifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+		HS256Secret: cfg.Auth.JWTSecret,
+		JWKSFile:    cfg.Auth.JWTJWKSFile,
+		Issuer:      cfg.Auth.JWTIssuer,
+		Audience:    cfg.Auth.JWTAudience,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure player tokens: %v", err)
+	}
+	if jwtVerifier != nil {
+		authenticator.WithJWT(jwtVerifier)
+		log.Println("✓ Player tokens (JWT) enabled")
+	}
+	if cfg.Auth.AdminAPIKey != "" {
+		key, err := authenticator.EnsureKey(context.Background(), "bootstrap admin", cfg.Auth.AdminAPIKey, []string{models.ScopeAdmin})
+		if err != nil {
+			log.Fatalf("Failed to bootstrap admin API key: %v", err)
+		}
+		if key != nil {
+			auditLog.Record(context.Background(), audit.Entry{
+				Action: audit.ActionKeyCreate,
+				Target: audit.KeyTarget(key.ID),
+				Reason: "ADMIN_API_KEY",
+				After:  map[string]interface{}{"name": key.Name, "prefix": key.Prefix, "scopes": key.ScopeList()},
+			})
+		}
+		log.Println("✓ Admin API key from ADMIN_API_KEY is active")
+	} else {
+		log.Println("⚠️ ADMIN_API_KEY not set; keys can only be managed with an existing admin key")
+	}
+
 	// Initialize Worker Pool for PostgreSQL persistence
 	workerCount := 20     // Number of worker goroutines
 	queueSize := 1000     // Buffered channel size
 	workerPool := worker.NewWorkerPool(workerCount, queueSize, postgresRepo)
 	workerPool.Start()
 
-	// Initialize WebSocket Hub
-	hub := websocket.NewHub(redisRepo, redisClient)
+	// Anti-cheat rules checked on every client submission
+	antiCheat, err := newAntiCheatEngine(cfg.Cheat, redisRepo)
+	if err != nil {
+		log.Fatalf("Invalid anti-cheat configuration: %v", err)
+	}
+	log.Printf("✓ Anti-cheat rules: %v", antiCheat.Rules())
+
+	// Initialize service with worker pool and redis client
+	leaderboardService := service.NewLeaderboardService(redisRepo, postgresRepo, workerPool, redisClient).
+		WithAntiCheat(antiCheat).
+		WithAudit(auditLog)
+
+	// Initialize WebSocket Hub (resolves client subscriptions through the service)
+	hub := websocket.NewHub(redisRepo, leaderboardService).WithShards(cfg.Hub.Shards)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go hub.Run(ctx)
 
-	// Initialize service with worker pool and redis client
-	leaderboardService := service.NewLeaderboardService(redisRepo, postgresRepo, workerPool, redisClient)
+	// Replay writes queued while Redis was unavailable (degraded mode)
+	go leaderboardService.RunRecovery(ctx)
 
 	// Initialize Simulation Manager (high-performance internal job)
 	simulatorConfig := jobs.SimulatorConfig{
@@ -90,15 +158,73 @@ func main() {
 		log.Printf("⚠️ Failed to start simulator: %v", err)
 	}
 
+	// Game servers sign submissions; nonces are tracked in Redis to reject replays
+	signatures := auth.NewSignatureVerifier(cfg.Auth.SigningSecret, time.Duration(cfg.Auth.SigningSkewSec)*time.Second, redisRepo)
+	if signatures != nil {
+		log.Printf("✓ Signed score submissions required (clock skew ±%s)", signatures.Skew())
+	}
+
+	// Rate limits per route, with token buckets in Redis shared by all instances
+	scoreLimits, err := ratelimit.ParseRules(cfg.Limits.Scores)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_SCORES: %v", err)
+	}
+	readLimits, err := ratelimit.ParseRules(cfg.Limits.Reads)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_READS: %v", err)
+	}
+	limiter := ratelimit.NewLimiter(redisRepo).
+		WithRoute(ratelimit.RouteScores, scoreLimits).
+		WithRoute(ratelimit.RouteReads, readLimits)
+
+	// Retried submissions with the same Idempotency-Key get the original response
+	idempotent := idempotency.NewStore(redisRepo, time.Duration(cfg.Server.IdempotencyTTLSec)*time.Second)
+
 	// Initialize handlers with hub
-	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService, hub)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService, hub).
+		WithSignatures(signatures).
+		WithRateLimiter(limiter).
+		WithIdempotency(idempotent)
+	adminHandler := handlers.NewAdminHandler(authenticator, leaderboardService)
+
+	// gRPC server sharing the service and the hub's change feed
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcapi.UnaryAuthInterceptor(authenticator)),
+		grpc.ChainStreamInterceptor(grpcapi.StreamAuthInterceptor(authenticator)),
+	)
+	grpcapi.NewServer(leaderboardService, hub).
+		WithSignatures(signatures).
+		WithRateLimiter(limiter).
+		Register(grpcServer)
+	if cfg.Server.GRPCPort > 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPCPort))
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		go func() {
+			log.Printf("🚀 gRPC server starting on port %d...", cfg.Server.GRPCPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Printf("gRPC server stopped: %v", err)
+			}
+		}()
+	}
 
 	// Create Fiber app
-	app := fiber.New(fiber.Config{
+	fiberConfig := fiber.Config{
 		AppName:               "Kinetix Leaderboard System",
 		DisableStartupMessage: false,
 		ErrorHandler:          customErrorHandler,
-	})
+	}
+	// c.IP() reads the proxy header only on requests forwarded by a trusted proxy, so
+	// clients cannot pick their own IP for the rate limiter
+	if proxies := cfg.GetTrustedProxies(); len(proxies) > 0 {
+		fiberConfig.ProxyHeader = cfg.Server.ProxyHeader
+		fiberConfig.EnableTrustedProxyCheck = true
+		fiberConfig.TrustedProxies = proxies
+		fiberConfig.EnableIPValidation = true
+		log.Printf("🔀 Client IPs read from %s behind trusted proxies %v", cfg.Server.ProxyHeader, proxies)
+	}
+	app := fiber.New(fiberConfig)
 
 	// Middleware
 	app.Use(recover.New())
@@ -107,26 +233,53 @@ func main() {
 		TimeFormat: "2006-01-02 15:04:05",
 	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  cfg.Auth.CORSOrigins,
+		AllowMethods:  "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Last-Event-ID, " + auth.APIKeyHeader + ", " +
+			auth.SignatureHeader + ", " + auth.SignatureNonceHeader + ", " + auth.SignatureTimestampHeader + ", " +
+			idempotency.Header,
+		ExposeHeaders: handlers.DegradedHeader + ", " + idempotency.ReplayedHeader + ", " + fiber.HeaderRetryAfter,
 	}))
 
 	// Routes
 	api := app.Group("/api/v1")
+	requireRead := authenticator.RequireRead()
+	limitReads := limiter.Middleware(ratelimit.RouteReads)
 	
 	// Leaderboard routes
-	api.Post("/scores", leaderboardHandler.UpdateScore)
-	api.Get("/leaderboard", leaderboardHandler.GetLeaderboard)
-	api.Get("/search/:username", leaderboardHandler.SearchUser)
+	api.Post("/scores", authenticator.RequireKeyOrPlayer(models.ScopeWriteScores), limiter.Middleware(ratelimit.RouteScores), idempotent.Middleware(), leaderboardHandler.UpdateScore)
+	api.Get("/leaderboard", requireRead, limitReads, leaderboardHandler.GetLeaderboard)
+	api.Get("/leaderboard/changes", requireRead, limitReads, leaderboardHandler.GetChanges)
+	api.Get("/leaderboard/wait", requireRead, limitReads, leaderboardHandler.WaitForChange)
+	api.Get("/search/:username", requireRead, limitReads, leaderboardHandler.SearchUser)
+	api.Get("/players/:username/history", authenticator.Identify(), limitReads, leaderboardHandler.GetPlayerHistory)
 	api.Get("/health", leaderboardHandler.HealthCheck)
+	api.Get("/metrics", requireRead, limitReads, leaderboardHandler.GetMetrics)
+	api.Get("/presence", requireRead, limitReads, leaderboardHandler.GetPresence)
+
+	// Server-Sent Events, an alternative to the WebSocket endpoint
+	api.Get("/stream", requireRead, limitReads, leaderboardHandler.Stream)
 	
 	// Debug routes (load simulation)
-	debug := api.Group("/debug")
+	debug := api.Group("/debug", authenticator.Require(models.ScopeAdmin))
 	debug.Post("/simulate", leaderboardHandler.SimulateLoad)
+
+	// Admin routes
+	admin := api.Group("/admin", authenticator.Require(models.ScopeAdmin))
+	admin.Post("/keys", adminHandler.CreateAPIKey)
+	admin.Get("/keys", adminHandler.ListAPIKeys)
+	admin.Delete("/keys/:id", adminHandler.RevokeAPIKey)
+	admin.Get("/reviews", adminHandler.ListReviews)
+	admin.Post("/reviews/:id/approve", adminHandler.ApproveReview)
+	admin.Post("/reviews/:id/reject", adminHandler.RejectReview)
+	admin.Post("/users/:username/moderation", adminHandler.ModerateUser)
+	admin.Get("/moderation", adminHandler.ListModeratedUsers)
+	admin.Post("/users/:username/rating", adminHandler.SetRating)
+	admin.Post("/rollback", adminHandler.Rollback)
+	admin.Get("/audit", adminHandler.ListAudit)
 	
 	// WebSocket route with upgrade middleware
-	app.Use("/ws", func(c *fiber.Ctx) error {
+	app.Use("/ws", requireRead, limitReads, func(c *fiber.Ctx) error {
 		// Check if it's a WebSocket upgrade request
 		if fiberws.IsWebSocketUpgrade(c) {
 			return c.Next()
@@ -135,8 +288,24 @@ func main() {
 	})
 	app.Get("/ws", fiberws.New(func(c *fiberws.Conn) {
 		leaderboardHandler.HandleWebSocket(c)
+	}, fiberws.Config{
+		// Clients pick JSON (default) or MessagePack via Sec-WebSocket-Protocol
+		Subprotocols: websocket.Subprotocols,
 	}))
 
+	// GraphQL: queries over HTTP, subscriptions over WebSocket (graphql-transport-ws)
+	graphqlHandler := graphqlapi.NewHandler(leaderboardService, hub)
+	graphqlWS := fiberws.New(graphqlHandler.ServeWS, fiberws.Config{
+		Subprotocols: graphqlapi.Subprotocols,
+	})
+	app.Post("/graphql", requireRead, limitReads, graphqlHandler.Query)
+	app.Get("/graphql", requireRead, limitReads, func(c *fiber.Ctx) error {
+		if fiberws.IsWebSocketUpgrade(c) {
+			return graphqlWS(c)
+		}
+		return graphqlHandler.Query(c)
+	})
+
 	// Root route
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -145,10 +314,27 @@ func main() {
 			"endpoints": []string{
 				"POST /api/v1/scores",
 				"GET /api/v1/leaderboard",
+				"GET /api/v1/leaderboard/changes?since=<version>",
+				"GET /api/v1/leaderboard/wait?version=<version>&timeout=30s",
 				"GET /api/v1/search/:username",
+				"GET /api/v1/players/:username/history (player token)",
 				"GET /api/v1/health",
+				"GET /api/v1/metrics",
+				"GET /api/v1/presence",
+				"GET /api/v1/stream (Server-Sent Events)",
 				"POST /api/v1/debug/simulate",
+				"POST|GET /api/v1/admin/keys",
+				"DELETE /api/v1/admin/keys/:id",
+				"GET /api/v1/admin/reviews?status=pending",
+				"POST /api/v1/admin/reviews/:id/approve|reject",
+				"POST /api/v1/admin/users/:username/moderation",
+				"GET /api/v1/admin/moderation?status=all",
+				"POST /api/v1/admin/users/:username/rating",
+				"POST /api/v1/admin/rollback",
+				"GET /api/v1/admin/audit?actor=&action=&target=&from=&to=",
 				"WS /ws (WebSocket)",
+				"POST /graphql (GraphQL; WS /graphql for subscriptions)",
+				"gRPC kinetix.leaderboard.v1.LeaderboardService",
 			},
 			"websocket_clients": hub.GetClientCount(),
 		})
@@ -174,6 +360,18 @@ func main() {
 			log.Printf("Server forced to shutdown: %v", err)
 		}
 
+		// Change streams never finish on their own, so don't wait on them for long
+		grpcStopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+		select {
+		case <-grpcStopped:
+		case <-time.After(5 * time.Second):
+			grpcServer.Stop()
+		}
+
 		// Third, gracefully shutdown worker pool (flush pending writes)
 		log.Println("🔄 Flushing worker pool (pending database writes)...")
 		if err := workerPool.Shutdown(30 * time.Second); err != nil {
@@ -199,6 +397,35 @@ func main() {
 	}
 }
 
+// newAntiCheatEngine builds the anti-cheat engine from the configured rules
+func newAntiCheatEngine(cfg config.AntiCheatConfig, redisRepo *repository.RedisRepository) (*anticheat.Engine, error) {
+	engine := anticheat.NewEngine(redisRepo)
+
+	if cfg.MaxJump > 0 {
+		action, err := anticheat.ParseAction(cfg.MaxJumpAction)
+		if err != nil {
+			return nil, err
+		}
+		engine.WithRule(anticheat.MaxJump{Max: cfg.MaxJump, OnViolation: action})
+	}
+	if cfg.MaxUpdatesPerMinute > 0 {
+		action, err := anticheat.ParseAction(cfg.MaxUpdatesAction)
+		if err != nil {
+			return nil, err
+		}
+		engine.WithRule(anticheat.UpdateRate{Max: cfg.MaxUpdatesPerMinute, Per: time.Minute, OnViolation: action})
+	}
+	if cfg.MaxChangePerHour > 0 {
+		action, err := anticheat.ParseAction(cfg.MaxChangePerHourAction)
+		if err != nil {
+			return nil, err
+		}
+		engine.WithRule(anticheat.Velocity{MaxChange: cfg.MaxChangePerHour, Per: time.Hour, OnViolation: action})
+	}
+
+	return engine, nil
+}
+
 // initPostgres initializes PostgreSQL connection with connection pooling
 func initPostgres(cfg *config.Config) (*gorm.DB, error) {
 	dsn := cfg.GetDSN()
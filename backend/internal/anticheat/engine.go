@@ -0,0 +1,206 @@
+// Package anticheat checks score submissions against rules that flag or block
+// suspicious updates before they reach the leaderboard
+package anticheat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+// Update is a score submission as seen by the rules
+type Update struct {
+	Username string
+	Previous *int // Current rating (nil for new users)
+	Rating   int
+	At       time.Time
+	Recent   []repository.RecentUpdate // Earlier applied submissions of the user within the longest rule window
+}
+
+// Rule checks one property of a submission
+// Check returns a human-readable reason when the submission violates the rule
+type Rule interface {
+	Name() string
+	Action() string        // models.ReviewActionFlag or models.ReviewActionBlock
+	Window() time.Duration // How much submission history the rule needs (0 = none)
+	Check(update Update) (string, bool)
+}
+
+// Finding is one violated rule
+type Finding struct {
+	Rule   string
+	Action string
+	Reason string
+}
+
+// Verdict is the outcome of checking a submission against every rule
+type Verdict struct {
+	Action   string // "" when no rule was violated, else the most severe action
+	Findings []Finding
+}
+
+// Reasons describes the findings for the review queue
+func (v Verdict) Reasons() string {
+	reasons := make([]string, len(v.Findings))
+	for i, finding := range v.Findings {
+		reasons[i] = fmt.Sprintf("%s: %s", finding.Rule, finding.Reason)
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// ruleCounters counts a rule's violations
+type ruleCounters struct {
+	rule       Rule
+	violations atomic.Int64
+}
+
+// Engine checks submissions against its rules
+type Engine struct {
+	repo   *repository.RedisRepository
+	rules  []*ruleCounters
+	window time.Duration
+
+	evaluated atomic.Int64
+	flagged   atomic.Int64
+	blocked   atomic.Int64
+	errors    atomic.Int64 // Submissions let through unchecked because history was unavailable
+}
+
+// NewEngine creates an engine without rules; add them with WithRule
+func NewEngine(repo *repository.RedisRepository) *Engine {
+	return &Engine{repo: repo}
+}
+
+// WithRule adds a rule to the engine
+func (e *Engine) WithRule(rule Rule) *Engine {
+	e.rules = append(e.rules, &ruleCounters{rule: rule})
+	if rule.Window() > e.window {
+		e.window = rule.Window()
+	}
+	return e
+}
+
+// Rules returns the names of the engine's rules
+func (e *Engine) Rules() []string {
+	names := make([]string, len(e.rules))
+	for i, counters := range e.rules {
+		names[i] = counters.rule.Name()
+	}
+	return names
+}
+
+// Evaluate checks a submission against every rule, then records it in the user's history
+// unless it was blocked: blocked submissions are not applied, so they must not count
+// towards later checks (Record adds them once approved)
+// When the history cannot be read (Redis down), the submission is let through unchecked
+// and an error is returned for logging
+func (e *Engine) Evaluate(ctx context.Context, username string, previous *int, rating int) (Verdict, error) {
+	if e == nil || len(e.rules) == 0 {
+		return Verdict{}, nil
+	}
+	e.evaluated.Add(1)
+
+	update := Update{
+		Username: username,
+		Previous: previous,
+		Rating:   rating,
+		At:       time.Now(),
+	}
+	if e.window > 0 {
+		recent, err := e.repo.RecentUpdates(ctx, username, e.window)
+		if err != nil {
+			e.errors.Add(1)
+			return Verdict{}, fmt.Errorf("failed to read submission history: %w", err)
+		}
+		update.Recent = recent
+	}
+
+	verdict := e.check(update)
+	if verdict.Action != models.ReviewActionBlock {
+		if err := e.record(ctx, update); err != nil {
+			return verdict, fmt.Errorf("failed to record submission history: %w", err)
+		}
+	}
+	return verdict, nil
+}
+
+// Record adds an applied submission to the user's history without checking it, e.g. a
+// blocked update approved by a reviewer
+func (e *Engine) Record(ctx context.Context, username string, previous *int, rating int) error {
+	if e == nil || len(e.rules) == 0 {
+		return nil
+	}
+	return e.record(ctx, Update{
+		Username: username,
+		Previous: previous,
+		Rating:   rating,
+		At:       time.Now(),
+	})
+}
+
+// record stores an update in the history when a rule needs it
+func (e *Engine) record(ctx context.Context, update Update) error {
+	if e.window == 0 {
+		return nil
+	}
+	return e.repo.RecordUpdate(ctx, update.Username, repository.RecentUpdate{
+		At:       update.At,
+		Previous: update.Previous,
+		Rating:   update.Rating,
+	}, e.window)
+}
+
+// check runs every rule against an update and counts the outcome
+func (e *Engine) check(update Update) Verdict {
+	var verdict Verdict
+	for _, counters := range e.rules {
+		reason, violated := counters.rule.Check(update)
+		if !violated {
+			continue
+		}
+		counters.violations.Add(1)
+		verdict.Findings = append(verdict.Findings, Finding{
+			Rule:   counters.rule.Name(),
+			Action: counters.rule.Action(),
+			Reason: reason,
+		})
+		if verdict.Action != models.ReviewActionBlock {
+			verdict.Action = counters.rule.Action()
+		}
+	}
+
+	switch verdict.Action {
+	case models.ReviewActionBlock:
+		e.blocked.Add(1)
+	case models.ReviewActionFlag:
+		e.flagged.Add(1)
+	}
+	return verdict
+}
+
+// GetMetrics returns how many submissions were checked, flagged and blocked, and the
+// violations per rule
+func (e *Engine) GetMetrics() map[string]interface{} {
+	if e == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	violations := make(map[string]int64, len(e.rules))
+	for _, counters := range e.rules {
+		violations[counters.rule.Name()] = counters.violations.Load()
+	}
+
+	return map[string]interface{}{
+		"enabled":    len(e.rules) > 0,
+		"evaluated":  e.evaluated.Load(),
+		"flagged":    e.flagged.Load(),
+		"blocked":    e.blocked.Load(),
+		"errors":     e.errors.Load(),
+		"violations": violations,
+	}
+}
@@ -0,0 +1,62 @@
+package anticheat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestEngine(t *testing.T) (*Engine, *repository.RedisRepository) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	repo := repository.NewRedisRepository(client)
+	engine := NewEngine(repo).
+		WithRule(MaxJump{Max: 500, OnViolation: models.ReviewActionBlock}).
+		WithRule(UpdateRate{Max: 10, Per: time.Minute, OnViolation: models.ReviewActionFlag})
+	return engine, repo
+}
+
+func TestEvaluateRecordsOnlyUnblockedUpdates(t *testing.T) {
+	ctx := context.Background()
+	engine, repo := newTestEngine(t)
+
+	verdict, err := engine.Evaluate(ctx, "alice", intPtr(1000), 1200)
+	if err != nil || verdict.Action != "" {
+		t.Fatalf("clean update: verdict %q, err %v", verdict.Action, err)
+	}
+
+	// Checked against the history without itself, then left out of it
+	verdict, err = engine.Evaluate(ctx, "alice", intPtr(1200), 5000)
+	if err != nil || verdict.Action != models.ReviewActionBlock {
+		t.Fatalf("jump: verdict %q, err %v, want block", verdict.Action, err)
+	}
+
+	recent, err := repo.RecentUpdates(ctx, "alice", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Rating != 1200 {
+		t.Fatalf("history = %+v, want only the clean update", recent)
+	}
+
+	// Approving the blocked update adds it
+	if err := engine.Record(ctx, "alice", intPtr(1200), 5000); err != nil {
+		t.Fatal(err)
+	}
+	recent, err = repo.RecentUpdates(ctx, "alice", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[1].Rating != 5000 || recent[1].Previous == nil || *recent[1].Previous != 1200 {
+		t.Fatalf("history = %+v, want the approved update last", recent)
+	}
+}
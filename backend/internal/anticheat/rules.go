@@ -0,0 +1,106 @@
+package anticheat
+
+import (
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+// ParseAction validates a configured rule action
+func ParseAction(action string) (string, error) {
+	switch action {
+	case models.ReviewActionFlag, models.ReviewActionBlock:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown anti-cheat action %q (want flag or block)", action)
+	}
+}
+
+// MaxJump catches a single update changing a rating by more than Max
+type MaxJump struct {
+	Max         int
+	OnViolation string
+}
+
+func (r MaxJump) Name() string          { return "max_jump" }
+func (r MaxJump) Action() string        { return r.OnViolation }
+func (r MaxJump) Window() time.Duration { return 0 }
+
+func (r MaxJump) Check(update Update) (string, bool) {
+	if update.Previous == nil {
+		return "", false
+	}
+	if jump := abs(update.Rating - *update.Previous); jump > r.Max {
+		return fmt.Sprintf("rating changed by %d in one update (max %d)", jump, r.Max), true
+	}
+	return "", false
+}
+
+// UpdateRate catches a user receiving more than Max updates within Per
+type UpdateRate struct {
+	Max         int
+	Per         time.Duration
+	OnViolation string
+}
+
+func (r UpdateRate) Name() string          { return "update_rate" }
+func (r UpdateRate) Action() string        { return r.OnViolation }
+func (r UpdateRate) Window() time.Duration { return r.Per }
+
+func (r UpdateRate) Check(update Update) (string, bool) {
+	count := 1 // This update
+	for _, recent := range update.Recent {
+		if update.At.Sub(recent.At) <= r.Per {
+			count++
+		}
+	}
+	if count > r.Max {
+		return fmt.Sprintf("%d updates within %s (max %d)", count, r.Per, r.Max), true
+	}
+	return "", false
+}
+
+// Velocity catches a rating moving by more than MaxChange within Per across updates,
+// such as a climb split into many small jumps
+type Velocity struct {
+	MaxChange   int
+	Per         time.Duration
+	OnViolation string
+}
+
+func (r Velocity) Name() string          { return "velocity" }
+func (r Velocity) Action() string        { return r.OnViolation }
+func (r Velocity) Window() time.Duration { return r.Per }
+
+func (r Velocity) Check(update Update) (string, bool) {
+	// The rating before the oldest update in the window is where the user started
+	var baseline *int
+	for _, recent := range update.Recent {
+		if update.At.Sub(recent.At) > r.Per {
+			continue
+		}
+		if recent.Previous != nil {
+			baseline = recent.Previous
+		} else {
+			rating := recent.Rating
+			baseline = &rating
+		}
+		break
+	}
+	if baseline == nil {
+		return "", false
+	}
+
+	if change := abs(update.Rating - *baseline); change > r.MaxChange {
+		return fmt.Sprintf("rating changed by %d within %s (max %d)", change, r.Per, r.MaxChange), true
+	}
+	return "", false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
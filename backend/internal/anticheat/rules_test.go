@@ -0,0 +1,113 @@
+package anticheat
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestMaxJump(t *testing.T) {
+	rule := MaxJump{Max: 500, OnViolation: models.ReviewActionBlock}
+	tests := []struct {
+		name     string
+		previous *int
+		rating   int
+		violated bool
+	}{
+		{"new user", nil, 9000, false},
+		{"small climb", intPtr(1000), 1200, false},
+		{"exactly max", intPtr(1000), 1500, false},
+		{"over max", intPtr(1000), 1501, true},
+		{"drop over max", intPtr(2000), 1000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, violated := rule.Check(Update{Previous: tt.previous, Rating: tt.rating, At: time.Now()})
+			if violated != tt.violated {
+				t.Errorf("violated = %v, want %v", violated, tt.violated)
+			}
+		})
+	}
+}
+
+func TestUpdateRate(t *testing.T) {
+	now := time.Now()
+	rule := UpdateRate{Max: 3, Per: time.Minute, OnViolation: models.ReviewActionFlag}
+	ago := func(d time.Duration) repository.RecentUpdate {
+		return repository.RecentUpdate{At: now.Add(-d), Rating: 1000}
+	}
+	tests := []struct {
+		name     string
+		recent   []repository.RecentUpdate
+		violated bool
+	}{
+		{"no history", nil, false},
+		{"at max", []repository.RecentUpdate{ago(30 * time.Second), ago(10 * time.Second)}, false},
+		{"over max", []repository.RecentUpdate{ago(50 * time.Second), ago(30 * time.Second), ago(10 * time.Second)}, true},
+		{"old updates ignored", []repository.RecentUpdate{ago(3 * time.Minute), ago(2 * time.Minute), ago(10 * time.Second)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, violated := rule.Check(Update{Rating: 1000, At: now, Recent: tt.recent})
+			if violated != tt.violated {
+				t.Errorf("violated = %v, want %v", violated, tt.violated)
+			}
+		})
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	now := time.Now()
+	rule := Velocity{MaxChange: 1000, Per: time.Hour, OnViolation: models.ReviewActionFlag}
+	tests := []struct {
+		name     string
+		recent   []repository.RecentUpdate
+		rating   int
+		violated bool
+	}{
+		{"no history", nil, 5000, false},
+		{
+			"small steps within max",
+			[]repository.RecentUpdate{
+				{At: now.Add(-30 * time.Minute), Previous: intPtr(1000), Rating: 1300},
+				{At: now.Add(-10 * time.Minute), Previous: intPtr(1300), Rating: 1600},
+			},
+			2000, false,
+		},
+		{
+			"small steps over max",
+			[]repository.RecentUpdate{
+				{At: now.Add(-30 * time.Minute), Previous: intPtr(1000), Rating: 1400},
+				{At: now.Add(-10 * time.Minute), Previous: intPtr(1400), Rating: 1800},
+			},
+			2200, true,
+		},
+		{
+			"baseline outside window",
+			[]repository.RecentUpdate{
+				{At: now.Add(-2 * time.Hour), Previous: intPtr(0), Rating: 1500},
+				{At: now.Add(-10 * time.Minute), Previous: intPtr(1500), Rating: 1800},
+			},
+			2200, false,
+		},
+		{
+			"new user starts from first rating",
+			[]repository.RecentUpdate{
+				{At: now.Add(-10 * time.Minute), Rating: 1000},
+			},
+			2500, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, violated := rule.Check(Update{Rating: tt.rating, At: now, Recent: tt.recent})
+			if violated != tt.violated {
+				t.Errorf("violated = %v, want %v", violated, tt.violated)
+			}
+		})
+	}
+}
@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"backend/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminHandler handles HTTP requests for the admin API (requires the admin scope)
+type AdminHandler struct {
+	auth      *auth.Authenticator
+	service   *service.LeaderboardService
+	validator *validator.Validate
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(authenticator *auth.Authenticator, service *service.LeaderboardService) *AdminHandler {
+	return &AdminHandler{
+		auth:      authenticator,
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// CreateAPIKey handles POST /api/v1/admin/keys
+// @Summary Create an API key
+// @Description Creates a key with the given scopes. The key is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAPIKeyRequest true "Key name and scopes"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.APIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/keys [post]
+func (h *AdminHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: err.Error(),
+		})
+	}
+
+	key, plaintext, err := h.auth.CreateKey(c.UserContext(), req.Name, req.Scopes)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create API key",
+			Message: err.Error(),
+		})
+	}
+
+	h.service.Audit().Record(c.UserContext(), audit.Entry{
+		Action: audit.ActionKeyCreate,
+		Target: audit.KeyTarget(key.ID),
+		After:  toAPIKeyResponse(key),
+	})
+
+	response := toAPIKeyResponse(key)
+	response.Key = plaintext
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// ListAPIKeys handles GET /api/v1/admin/keys
+// @Summary List API keys
+// @Description Lists every key, including revoked ones. Keys themselves are never returned.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.APIKeyResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/keys [get]
+func (h *AdminHandler) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.auth.ListKeys(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to list API keys",
+			Message: err.Error(),
+		})
+	}
+
+	response := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		response[i] = toAPIKeyResponse(&keys[i])
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/admin/keys/:id
+// @Summary Revoke an API key
+// @Description Revokes a key. Other instances stop accepting it within 30 seconds.
+// @Tags admin
+// @Produce json
+// @Param id path int true "Key ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.APIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/keys/{id} [delete]
+func (h *AdminHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid key ID",
+			Message: "id must be a positive integer",
+		})
+	}
+
+	key, err := h.auth.RevokeKey(c.UserContext(), uint(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "API key not found",
+				Message: "no active key with this ID",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to revoke API key",
+			Message: err.Error(),
+		})
+	}
+
+	h.service.Audit().Record(c.UserContext(), audit.Entry{
+		Action: audit.ActionKeyRevoke,
+		Target: audit.KeyTarget(key.ID),
+		Before: map[string]interface{}{"revoked_at": nil},
+		After:  map[string]interface{}{"revoked_at": key.RevokedAt},
+	})
+
+	return c.Status(fiber.StatusOK).JSON(toAPIKeyResponse(key))
+}
+
+// ListReviews handles GET /api/v1/admin/reviews
+// @Summary List anti-cheat reviews
+// @Description Lists updates flagged or blocked by the anti-cheat rules, oldest first
+// @Tags admin
+// @Produce json
+// @Param status query string false "pending (default), approved, rejected or all"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Number of results to return" default(50)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ReviewListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reviews [get]
+func (h *AdminHandler) ListReviews(c *fiber.Ctx) error {
+	status := c.Query("status", models.ReviewPending)
+	switch status {
+	case models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+	case "all":
+		status = ""
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid status",
+			Message: "status must be pending, approved, rejected or all",
+		})
+	}
+
+	offset := c.QueryInt("offset", 0)
+	limit := c.QueryInt("limit", 50)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	reviews, err := h.service.ListReviews(c.UserContext(), status, offset, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to list reviews",
+			Message: err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(reviews)
+}
+
+// ApproveReview handles POST /api/v1/admin/reviews/:id/approve
+// @Summary Approve a reviewed update
+// @Description Approves a pending review. A blocked update is applied; a flagged one stays.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body models.ResolveReviewRequest false "Reviewer note"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ScoreReview
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reviews/{id}/approve [post]
+func (h *AdminHandler) ApproveReview(c *fiber.Ctx) error {
+	return h.resolveReview(c, true)
+}
+
+// RejectReview handles POST /api/v1/admin/reviews/:id/reject
+// @Summary Reject a reviewed update
+// @Description Rejects a pending review. A blocked update is dropped; a flagged one is
+// @Description reverted to the previous rating unless the user has been updated since.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body models.ResolveReviewRequest false "Reviewer note"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ScoreReview
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/reviews/{id}/reject [post]
+func (h *AdminHandler) RejectReview(c *fiber.Ctx) error {
+	return h.resolveReview(c, false)
+}
+
+// resolveReview approves or rejects the review named in the path
+func (h *AdminHandler) resolveReview(c *fiber.Ctx, approve bool) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid review ID",
+			Message: "id must be a positive integer",
+		})
+	}
+
+	var req models.ResolveReviewRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request body",
+				Message: err.Error(),
+			})
+		}
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: err.Error(),
+		})
+	}
+
+	review, err := h.service.ResolveReview(c.UserContext(), uint(id), approve, auth.KeyID(auth.KeyFromFiber(c)), req.Note)
+	if err != nil {
+		if errors.Is(err, repository.ErrReviewNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "Review not found",
+				Message: "no pending review with this ID",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to resolve review",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(review)
+}
+
+// toAPIKeyResponse describes a key without its hash
+func toAPIKeyResponse(key *models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// ModerateUser handles POST /api/v1/admin/users/:username/moderation
+// @Summary Moderate a user
+// @Description Sets a user's status. Hidden, banned and shadow-banned users leave the public
+// @Description leaderboard; banned users can no longer submit scores; shadow-banned users
+// @Description still see themselves ranked. Setting active restores the user.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param username path string true "Username"
+// @Param request body models.ModerationRequest true "Status and reason"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/users/{username}/moderation [post]
+func (h *AdminHandler) ModerateUser(c *fiber.Ctx) error {
+	var req models.ModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: err.Error(),
+		})
+	}
+
+	user, err := h.service.SetUserStatus(c.UserContext(), c.Params("username"), req.Status, req.Reason)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "User not found",
+				Message: "no user with this username",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to moderate user",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(user)
+}
+
+// ListModeratedUsers handles GET /api/v1/admin/moderation
+// @Summary List moderated users
+// @Description Lists users that are hidden, banned or shadow-banned, most recently moderated first
+// @Tags admin
+// @Produce json
+// @Param status query string false "hidden, banned, shadow-banned or all (default)"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Number of results to return" default(50)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ModeratedUsersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/moderation [get]
+func (h *AdminHandler) ListModeratedUsers(c *fiber.Ctx) error {
+	status := c.Query("status", "all")
+	switch status {
+	case models.UserHidden, models.UserBanned, models.UserShadowBanned:
+	case "all":
+		status = ""
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid status",
+			Message: "status must be hidden, banned, shadow-banned or all",
+		})
+	}
+
+	offset := c.QueryInt("offset", 0)
+	limit := c.QueryInt("limit", 50)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	users, err := h.service.ListModeratedUsers(c.UserContext(), status, offset, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to list moderated users",
+			Message: err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(users)
+}
+
+// SetRating handles POST /api/v1/admin/users/:username/rating
+// @Summary Override a user's rating
+// @Description Sets an existing user's rating, bypassing the anti-cheat rules. The reason is
+// @Description recorded in the user's score history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param username path string true "Username"
+// @Param request body models.SetRatingRequest true "Rating and reason"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ScoreEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/users/{username}/rating [post]
+func (h *AdminHandler) SetRating(c *fiber.Ctx) error {
+	var req models.SetRatingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: err.Error(),
+		})
+	}
+
+	event, err := h.service.SetRating(c.UserContext(), c.Params("username"), req.Rating, auth.KeyID(auth.KeyFromFiber(c)), req.Reason)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "User not found",
+				Message: "no user with this username",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to set rating",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(event)
+}
+
+// Rollback handles POST /api/v1/admin/rollback
+// @Summary Roll back score updates
+// @Description Reverts the updates of the given users, in a time window, or both. Each user
+// @Description goes back to their rating before their first matched update; users created by
+// @Description a matched update are removed. Use dry_run to preview the changes.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body models.RollbackRequest true "Users, window and reason"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RollbackResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /api/v1/admin/rollback [post]
+func (h *AdminHandler) Rollback(c *fiber.Ctx) error {
+	var req models.RollbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: err.Error(),
+		})
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "from must be before to",
+		})
+	}
+
+	result, err := h.service.Rollback(c.UserContext(), req, auth.KeyID(auth.KeyFromFiber(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRollbackUnfiltered):
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Validation failed",
+				Message: err.Error(),
+			})
+		case errors.Is(err, service.ErrRollbackUnavailable):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+				Error:   "Rollback unavailable",
+				Message: err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to roll back scores",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// ListAudit handles GET /api/v1/admin/audit
+// @Summary List the audit log
+// @Description Lists recorded admin and key management actions, newest first
+// @Tags admin
+// @Produce json
+// @Param actor query string false "Actor, e.g. key:3 or system"
+// @Param action query string false "Action, e.g. user.set_rating"
+// @Param target query string false "Target, e.g. user:alice or key:3"
+// @Param from query string false "Only entries at or after this time (RFC 3339)"
+// @Param to query string false "Only entries before this time (RFC 3339)"
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Number of results to return" default(50)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AuditListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/admin/audit [get]
+func (h *AdminHandler) ListAudit(c *fiber.Ctx) error {
+	from, err := timeQuery(c, "from")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid from",
+			Message: err.Error(),
+		})
+	}
+	to, err := timeQuery(c, "to")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid to",
+			Message: err.Error(),
+		})
+	}
+	filter := repository.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		From:   from,
+		To:     to,
+	}
+
+	offset := c.QueryInt("offset", 0)
+	limit := c.QueryInt("limit", 50)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	entries, err := h.service.Audit().List(c.UserContext(), filter, offset, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to list audit entries",
+			Message: err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
+
+// timeQuery parses an optional RFC 3339 query parameter
+func timeQuery(c *fiber.Ctx, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time", name)
+	}
+	return &t, nil
+}
@@ -1,21 +1,36 @@
 package handlers
 
 import (
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/idempotency"
 	"backend/internal/models"
+	"backend/internal/ratelimit"
 	"backend/internal/service"
 	"backend/internal/websocket"
+	"bufio"
+	"context"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	fiberws "github.com/gofiber/websocket/v2"
 )
 
+// DegradedHeader is set on responses served while Redis is unavailable
+const DegradedHeader = "X-Leaderboard-Degraded"
+
 // LeaderboardHandler handles HTTP requests for the leaderboard
 type LeaderboardHandler struct {
 	service   *service.LeaderboardService
 	validator *validator.Validate
 	hub       *websocket.Hub
+
+	signatures *auth.SignatureVerifier // nil when submissions need not be signed
+	limiter    *ratelimit.Limiter      // Reported in metrics; enforced by its middleware
+	idempotent *idempotency.Store      // Reported in metrics; enforced by its middleware
 }
 
 // NewLeaderboardHandler creates a new leaderboard handler
@@ -27,15 +42,50 @@ func NewLeaderboardHandler(service *service.LeaderboardService, hub *websocket.H
 	}
 }
 
+// WithIdempotency reports how the store handled duplicate requests in the metrics
+func (h *LeaderboardHandler) WithIdempotency(s *idempotency.Store) *LeaderboardHandler {
+	h.idempotent = s
+	return h
+}
+
+// WithRateLimiter reports the limiter's decisions in the metrics
+func (h *LeaderboardHandler) WithRateLimiter(l *ratelimit.Limiter) *LeaderboardHandler {
+	h.limiter = l
+	return h
+}
+
+// WithSignatures requires score submissions to be HMAC-signed, checked by v
+func (h *LeaderboardHandler) WithSignatures(v *auth.SignatureVerifier) *LeaderboardHandler {
+	h.signatures = v
+	return h
+}
+
 // UpdateScore handles POST /api/v1/scores
 // @Summary Update user score
-// @Description Creates or updates a user's rating
+// @Description Creates or updates a user's rating. Requires an API key with the write-scores
+// @Description scope, or a player token whose subject is the username being updated.
+// @Description When signing is enabled, API-key submissions must carry an HMAC signature, nonce
+// @Description and timestamp. Player-token submissions are not signed: players cannot hold the
+// @Description shared secret, and their token only allows their own username.
 // @Accept json
 // @Produce json
 // @Param request body models.ScoreRequest true "Score update request"
+// @Param Idempotency-Key header string false "Replays the original response for retries with the same key"
+// @Param X-Signature header string false "Hex HMAC-SHA256 of username, rating, nonce and timestamp (API-key callers)"
+// @Param X-Signature-Nonce header string false "Single-use nonce"
+// @Param X-Signature-Timestamp header string false "Unix seconds"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{} "Held for review by the anti-cheat rules"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /api/v1/scores [post]
 func (h *LeaderboardHandler) UpdateScore(c *fiber.Ctx) error {
 	var req models.ScoreRequest
@@ -57,14 +107,63 @@ func (h *LeaderboardHandler) UpdateScore(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update score via service
-	if err := h.service.UpdateScore(c.Context(), req.Username, req.Rating); err != nil {
+	// Players may only submit their own score
+	if player := auth.PlayerFromFiber(c); player != "" && player != req.Username {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:   "Forbidden",
+			Message: "token subject does not match username",
+		})
+	}
+
+	// Reject tampered or replayed submissions from game servers
+	if h.signatures.Required(auth.PlayerFromFiber(c)) {
+		sig := auth.Signature{
+			Value:     c.Get(auth.SignatureHeader),
+			Nonce:     c.Get(auth.SignatureNonceHeader),
+			Timestamp: c.Get(auth.SignatureTimestampHeader),
+		}
+		if err := h.signatures.Verify(c.Context(), req.Username, req.Rating, sig); err != nil {
+			if errors.Is(err, auth.ErrNonceStoreUnavailable) {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+					Error:   "Signature check unavailable",
+					Message: err.Error(),
+				})
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:   "Invalid signature",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	// Update score via service (after the anti-cheat rules), attributed to the key that
+	// authorised the request
+	review, err := h.service.SubmitScore(c.Context(), req.Username, req.Rating, auth.KeyID(auth.KeyFromFiber(c)))
+	if err != nil {
+		if errors.Is(err, service.ErrUserBanned) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "user is banned",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "Failed to update score",
 			Message: err.Error(),
 		})
 	}
 
+	h.setDegradedHeader(c)
+
+	// Blocked updates wait for a reviewer; flagged ones are applied without telling the client
+	if review != nil && review.Action == models.ReviewActionBlock {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":   "Score held for review",
+			"username":  req.Username,
+			"rating":    req.Rating,
+			"review_id": review.ID,
+		})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message":  "Score updated successfully",
 		"username": req.Username,
@@ -98,8 +197,8 @@ func (h *LeaderboardHandler) GetLeaderboard(c *fiber.Ctx) error {
 		limit = 100 // Max limit to prevent abuse
 	}
 
-	// Get leaderboard from service
-	leaderboard, err := h.service.GetLeaderboard(c.Context(), offset, limit)
+	// Get leaderboard from service, as seen by the calling player (if any)
+	leaderboard, err := h.service.GetLeaderboardFor(c.Context(), auth.PlayerFromFiber(c), offset, limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "Failed to retrieve leaderboard",
@@ -112,10 +211,195 @@ func (h *LeaderboardHandler) GetLeaderboard(c *fiber.Ctx) error {
 	c.Set("Pragma", "no-cache")
 	c.Set("Expires", "0")
 	c.Set("X-Content-Type-Options", "nosniff")
+	h.setDegradedHeader(c)
 
 	return c.Status(fiber.StatusOK).JSON(leaderboard)
 }
 
+// GetChanges handles GET /api/v1/leaderboard/changes
+// @Summary Get leaderboard changes since a version
+// @Description Returns users whose ratings changed after the given version, with their current ranks
+// @Accept json
+// @Produce json
+// @Param since query int true "Last version seen by the client"
+// @Success 200 {object} models.ChangesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /api/v1/leaderboard/changes [get]
+func (h *LeaderboardHandler) GetChanges(c *fiber.Ctx) error {
+	since, err := strconv.ParseInt(c.Query("since"), 10, 64)
+	if err != nil || since < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid version",
+			Message: "since must be a non-negative integer",
+		})
+	}
+
+	changes, err := h.service.GetChangesSince(c.Context(), since)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, service.ErrChangeFeedUnavailable) {
+			status = fiber.StatusServiceUnavailable
+			h.setDegradedHeader(c)
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "Failed to retrieve changes",
+			Message: err.Error(),
+		})
+	}
+
+	c.Set("Cache-Control", "no-cache, no-store, must-revalidate, private, max-age=0")
+
+	return c.Status(fiber.StatusOK).JSON(changes)
+}
+
+// Long-poll timeout bounds for GET /api/v1/leaderboard/wait
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 60 * time.Second
+)
+
+// WaitForChange handles GET /api/v1/leaderboard/wait
+// @Summary Long-poll for a leaderboard change
+// @Description Blocks until the leaderboard version differs from the given version or the timeout elapses.
+// @Description Driven by the WebSocket hub's change notifications; with page=true the current page is included.
+// @Produce json
+// @Param version query int true "Last version seen by the client"
+// @Param timeout query string false "Maximum wait, e.g. 30s (max 60s)" default(30s)
+// @Param page query bool false "Include the leaderboard page when the version changed"
+// @Param offset query int false "Offset of the included page" default(0)
+// @Param limit query int false "Limit of the included page" default(50)
+// @Success 200 {object} models.WaitResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/leaderboard/wait [get]
+func (h *LeaderboardHandler) WaitForChange(c *fiber.Ctx) error {
+	version, err := strconv.ParseInt(c.Query("version"), 10, 64)
+	if err != nil || version < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid version",
+			Message: "version must be a non-negative integer",
+		})
+	}
+
+	timeout := defaultWaitTimeout
+	if raw := c.Query("timeout"); raw != "" {
+		timeout, err = time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid timeout",
+				Message: "timeout must be a positive duration such as 30s",
+			})
+		}
+		if timeout > maxWaitTimeout {
+			timeout = maxWaitTimeout
+		}
+	}
+
+	// The request context is cancelled on server shutdown, releasing waiters early
+	ctx, cancel := context.WithTimeout(c.Context(), timeout)
+	defer cancel()
+
+	current, err := h.hub.WaitForVersion(ctx, version)
+	response := models.WaitResponse{
+		Version: current,
+		Changed: err == nil,
+	}
+
+	c.Set("Cache-Control", "no-cache, no-store, must-revalidate, private, max-age=0")
+
+	if response.Changed && c.QueryBool("page") {
+		offset, err := strconv.Atoi(c.Query("offset", "0"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		limit, err := strconv.Atoi(c.Query("limit", "50"))
+		if err != nil || limit <= 0 {
+			limit = 50
+		}
+		if limit > 100 {
+			limit = 100 // Max limit to prevent abuse
+		}
+
+		leaderboard, err := h.service.GetLeaderboardFor(c.Context(), auth.PlayerFromFiber(c), offset, limit)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "Failed to retrieve leaderboard",
+				Message: err.Error(),
+			})
+		}
+		response.Leaderboard = leaderboard
+		h.setDegradedHeader(c)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// Score history page size bounds for GET /api/v1/players/:username/history
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// GetPlayerHistory handles GET /api/v1/players/:username/history
+// @Summary Get a player's score history
+// @Description Returns the player's latest rating changes, newest first. Private: requires
+// @Description a player token for this username or an admin API key.
+// @Produce json
+// @Param username path string true "Username"
+// @Param limit query int false "Number of changes" default(20)
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ScoreHistoryResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api/v1/players/{username}/history [get]
+func (h *LeaderboardHandler) GetPlayerHistory(c *fiber.Ctx) error {
+	username := c.Params("username")
+	ctx := c.UserContext()
+
+	if !auth.CanViewPrivate(ctx, username) {
+		if auth.PlayerFromContext(ctx) == "" && auth.KeyFromContext(ctx) == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "a player token is required",
+			})
+		}
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:   "Forbidden",
+			Message: "score history is only visible to the player",
+		})
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	history, err := h.service.GetScoreHistory(ctx, []string{username}, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to retrieve score history",
+			Message: err.Error(),
+		})
+	}
+
+	events := history[username]
+	if events == nil {
+		events = []models.ScoreEvent{}
+	}
+	return c.Status(fiber.StatusOK).JSON(models.ScoreHistoryResponse{
+		Username: username,
+		History:  events,
+	})
+}
+
 // SearchUser handles GET /api/v1/search/:username
 // @Summary Search for a user
 // @Description Retrieves a user's global rank and rating
@@ -139,7 +423,7 @@ func (h *LeaderboardHandler) SearchUser(c *fiber.Ctx) error {
 	}
 
 	// Search for user
-	result, err := h.service.SearchUser(c.Context(), username)
+	result, err := h.service.SearchUserFor(c.Context(), auth.PlayerFromFiber(c), username)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
 			Error:   "User not found",
@@ -147,6 +431,8 @@ func (h *LeaderboardHandler) SearchUser(c *fiber.Ctx) error {
 		})
 	}
 
+	h.setDegradedHeader(c)
+
 	return c.Status(fiber.StatusOK).JSON(result)
 }
 
@@ -156,19 +442,30 @@ func (h *LeaderboardHandler) SearchUser(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
-// @Failure 503 {object} models.ErrorResponse
+// @Failure 503 {object} map[string]interface{}
 // @Router /api/v1/health [get]
 func (h *LeaderboardHandler) HealthCheck(c *fiber.Ctx) error {
 	if err := h.service.HealthCheck(c.Context()); err != nil {
-		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
-			Error:   "Health check failed",
-			Message: err.Error(),
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":    "Health check failed",
+			"message":  err.Error(),
+			"breakers": h.service.BreakerStates(),
+		})
+	}
+
+	if h.service.IsDegraded() {
+		h.setDegradedHeader(c)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":   "degraded",
+			"message":  "Redis unavailable, serving from PostgreSQL",
+			"breakers": h.service.BreakerStates(),
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "healthy",
-		"message": "All systems operational",
+		"status":   "healthy",
+		"message":  "All systems operational",
+		"breakers": h.service.BreakerStates(),
 	})
 }
 
@@ -180,6 +477,9 @@ func (h *LeaderboardHandler) HealthCheck(c *fiber.Ctx) error {
 // @Success 200 {object} map[string]interface{}
 // @Router /api/v1/debug/simulate [post]
 func (h *LeaderboardHandler) SimulateLoad(c *fiber.Ctx) error {
+	// Admin-only debug calls are audited even though this one no longer does anything
+	h.service.Audit().Record(c.UserContext(), audit.Entry{Action: audit.ActionDebugSimulate})
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "deprecated",
 		"message": "This endpoint is deprecated. Simulation runs automatically on server start.",
@@ -190,9 +490,95 @@ func (h *LeaderboardHandler) SimulateLoad(c *fiber.Ctx) error {
 // HandleWebSocket handles WebSocket connections at /ws
 // @Summary WebSocket endpoint for real-time leaderboard updates
 // @Description Upgrade HTTP connection to WebSocket for receiving real-time leaderboard updates
+// @Param version query int false "Last version seen, to resume a dropped session"
+// @Param snapshot query int false "Push ranks 1..N as the first message"
+// @Param range query string false "Push ranks start-end first and subscribe to them"
 // @Router /ws [get]
 func (h *LeaderboardHandler) HandleWebSocket(c *fiberws.Conn) {
+	opts := websocket.ParseConnectOptions(func(key string) string {
+		return c.Query(key)
+	})
+	// Player authenticated by the upgrade request's token, if any
+	opts.Player = auth.PlayerFromContext(auth.FromLocals(context.Background(), c.Locals))
+
 	// Connection is already upgraded by Fiber WebSocket middleware
 	// Serve the WebSocket connection through our hub
-	websocket.ServeWS(h.hub, c)
+	websocket.ServeWS(h.hub, c, opts)
+}
+
+// Stream handles Server-Sent Events connections at /api/v1/stream
+// @Summary Server-Sent Events stream of leaderboard updates
+// @Description Streams the same VERSION_UPDATE, DELTA, RESYNC, SNAPSHOT and SUBSCRIPTION_UPDATE messages as the WebSocket endpoint.
+// @Description Each event id is the leaderboard version, so EventSource resumes automatically via Last-Event-ID.
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "Last version seen, to resume a dropped stream (overrides version)"
+// @Param version query int false "Last version seen, to resume a dropped stream"
+// @Param snapshot query int false "Push ranks 1..N as the first event"
+// @Param range query string false "Push ranks start-end first and subscribe to them"
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/v1/stream [get]
+func (h *LeaderboardHandler) Stream(c *fiber.Ctx) error {
+	opts := websocket.ParseConnectOptions(func(key string) string {
+		return c.Query(key)
+	})
+	opts.Player = auth.PlayerFromFiber(c)
+
+	if lastEventID := c.Get("Last-Event-ID"); lastEventID != "" {
+		version, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || version < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid Last-Event-ID",
+				Message: "Last-Event-ID must be a non-negative version",
+			})
+		}
+		opts.LastVersion = version
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+
+	// The stream writer runs after the handler returns, on the connection's goroutine
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		websocket.ServeSSE(h.hub, w, opts)
+	})
+	return nil
+}
+
+// GetPresence handles GET /api/v1/presence
+// @Summary Online viewer counts
+// @Description Returns the number of WebSocket and SSE viewers of the leaderboard and of each subscribed rank range,
+// @Description aggregated across server instances and refreshed every few seconds
+// @Produce json
+// @Success 200 {object} websocket.Presence
+// @Router /api/v1/presence [get]
+func (h *LeaderboardHandler) GetPresence(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.hub.GetPresence())
+}
+
+// GetMetrics handles GET /api/v1/metrics
+// @Summary Real-time delivery and submission metrics
+// @Description Returns connected clients by transport, hub delivery counters and
+// @Description signed submission counts by rejection reason, rate limiter decisions per route
+// @Description idempotency key outcomes, anti-cheat verdicts and audit log writes
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/metrics [get]
+func (h *LeaderboardHandler) GetMetrics(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"hub":         h.hub.GetMetrics(),
+		"signatures":  h.signatures.GetMetrics(),
+		"rate_limits": h.limiter.GetMetrics(),
+		"idempotency": h.idempotent.GetMetrics(),
+		"anticheat":   h.service.AntiCheat().GetMetrics(),
+		"audit":       h.service.Audit().GetMetrics(),
+	})
+}
+
+// setDegradedHeader marks the response as served from the degraded (PostgreSQL) path
+func (h *LeaderboardHandler) setDegradedHeader(c *fiber.Ctx) {
+	if h.service.IsDegraded() {
+		c.Set(DegradedHeader, "true")
+	}
 }
@@ -0,0 +1,165 @@
+// Package audit records administrative and security-relevant actions in the append-only
+// audit_log table
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+// Audited actions
+const (
+	ActionKeyCreate      = "api_key.create"
+	ActionKeyRevoke      = "api_key.revoke"
+	ActionReviewApprove  = "review.approve"
+	ActionReviewReject   = "review.reject"
+	ActionUserModerate   = "user.moderate"
+	ActionUserSetRating  = "user.set_rating"
+	ActionUserDelete     = "user.delete"
+	ActionScoresRollback = "scores.rollback"
+	ActionSync           = "leaderboard.sync"
+	ActionDebugSimulate  = "debug.simulate"
+)
+
+// SystemActor is the actor of actions taken without an authenticated caller, such as
+// startup tasks
+const SystemActor = "system"
+
+// Entry is an action to record
+// Before and After are stored as JSON; nil means there was nothing (e.g. before a creation)
+type Entry struct {
+	Action string
+	Target string
+	Reason string
+	Before interface{}
+	After  interface{}
+}
+
+// Log writes audit entries to PostgreSQL
+type Log struct {
+	repo *repository.PostgresRepository
+
+	recorded atomic.Int64
+	failed   atomic.Int64 // Entries lost because PostgreSQL was unavailable
+}
+
+// NewLog creates an audit log backed by PostgreSQL
+func NewLog(repo *repository.PostgresRepository) *Log {
+	return &Log{repo: repo}
+}
+
+// Record appends entries attributed to the caller authenticated in ctx
+// The actions have already happened, so failures are logged and counted, not returned.
+func (l *Log) Record(ctx context.Context, entries ...Entry) {
+	rows := l.Rows(ctx, entries...)
+	if len(rows) == 0 {
+		return
+	}
+
+	// Recorded even if the caller goes away, since the action has already happened
+	if err := l.repo.CreateAuditEntries(context.WithoutCancel(ctx), rows); err != nil {
+		l.failed.Add(int64(len(rows)))
+		log.Printf("❌ Failed to write %d audit entries (%s by %s): %v", len(rows), rows[0].Action, rows[0].Actor, err)
+		return
+	}
+	l.recorded.Add(int64(len(rows)))
+}
+
+// Rows builds the rows of entries attributed to the caller authenticated in ctx, for
+// writes that insert them in their own transaction; report them with Committed
+// A nil log returns no rows.
+func (l *Log) Rows(ctx context.Context, entries ...Entry) []models.AuditEntry {
+	if l == nil || len(entries) == 0 {
+		return nil
+	}
+
+	key := auth.KeyFromContext(ctx)
+	actor := auth.ClientID(key, auth.PlayerFromContext(ctx))
+	if actor == "" {
+		actor = SystemActor
+	}
+	var apiKeyID *uint
+	if id := auth.KeyID(key); id != 0 {
+		apiKeyID = &id
+	}
+
+	rows := make([]models.AuditEntry, len(entries))
+	for i, entry := range entries {
+		rows[i] = models.AuditEntry{
+			Actor:    actor,
+			APIKeyID: apiKeyID,
+			Action:   entry.Action,
+			Target:   entry.Target,
+			Reason:   entry.Reason,
+			Before:   encode(entry.Before),
+			After:    encode(entry.After),
+		}
+	}
+	return rows
+}
+
+// Committed counts rows built by Rows once the transaction writing them has committed
+func (l *Log) Committed(rows []models.AuditEntry) {
+	if l == nil {
+		return
+	}
+	l.recorded.Add(int64(len(rows)))
+}
+
+// List returns a page of the audit log, newest first
+func (l *Log) List(ctx context.Context, filter repository.AuditFilter, offset, limit int) (*models.AuditListResponse, error) {
+	entries, total, err := l.repo.ListAuditEntries(ctx, filter, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	return &models.AuditListResponse{Data: entries, Offset: offset, Limit: limit, Total: total}, nil
+}
+
+// GetMetrics returns how many entries were recorded and lost
+func (l *Log) GetMetrics() map[string]interface{} {
+	if l == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	return map[string]interface{}{
+		"enabled":  true,
+		"recorded": l.recorded.Load(),
+		"failed":   l.failed.Load(),
+	}
+}
+
+// UserTarget names a user as an audit target
+func UserTarget(username string) string {
+	return "user:" + username
+}
+
+// KeyTarget names an API key as an audit target
+func KeyTarget(id uint) string {
+	return fmt.Sprintf("key:%d", id)
+}
+
+// ReviewTarget names a review as an audit target
+func ReviewTarget(id uint) string {
+	return fmt.Sprintf("review:%d", id)
+}
+
+// encode marshals a before or after state, or returns nil for none
+func encode(state interface{}) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return data
+}
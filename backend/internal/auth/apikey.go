@@ -0,0 +1,262 @@
+// Package auth authenticates API keys and enforces their scopes for the HTTP and
+// gRPC APIs
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+const (
+	// keyPrefix marks leaderboard API keys so they are recognisable in logs and scanners
+	keyPrefix = "kx_"
+
+	// Characters of the key kept in plaintext to identify it in listings
+	displayPrefixLength = len(keyPrefix) + 8
+
+	// Authenticated keys are cached for this long, so a revocation takes up to this
+	// long to reach other instances
+	keyCacheTTL = 30 * time.Second
+)
+
+var (
+	// ErrMissingAPIKey is returned when a request carries no API key
+	ErrMissingAPIKey = errors.New("missing API key")
+
+	// ErrInvalidAPIKey is returned for unknown or revoked keys
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// cachedKey is an authenticated key and when it must be re-checked
+type cachedKey struct {
+	key     *models.APIKey
+	expires time.Time
+}
+
+// Authenticator verifies API keys against their hashes in PostgreSQL and manages keys
+type Authenticator struct {
+	repo           *repository.PostgresRepository
+	requireReadKey bool
+	jwt            *JWTVerifier // nil when player tokens are not configured
+
+	mu    sync.Mutex
+	cache map[string]cachedKey // Key hash -> key
+}
+
+// NewAuthenticator creates an authenticator
+// When requireReadKey is false, read endpoints stay public and only writes need a key
+func NewAuthenticator(repo *repository.PostgresRepository, requireReadKey bool) *Authenticator {
+	return &Authenticator{
+		repo:           repo,
+		requireReadKey: requireReadKey,
+		cache:          make(map[string]cachedKey),
+	}
+}
+
+// WithJWT enables player tokens, verified by v
+func (a *Authenticator) WithJWT(v *JWTVerifier) *Authenticator {
+	a.jwt = v
+	return a
+}
+
+// VerifyPlayerToken verifies a player token and returns the player's username
+func (a *Authenticator) VerifyPlayerToken(token string) (string, error) {
+	return a.jwt.Verify(token)
+}
+
+// RequireReadKey reports whether reads need a key with the read scope
+func (a *Authenticator) RequireReadKey() bool {
+	return a.requireReadKey
+}
+
+// HashKey returns the SHA-256 hash stored for a key
+// Keys are random 256-bit values, so a fast hash is enough to make a leaked table useless
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKey returns a new random key
+func generateKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Authenticate returns the active key matching the plaintext key
+func (a *Authenticator) Authenticate(ctx context.Context, plaintext string) (*models.APIKey, error) {
+	if plaintext == "" {
+		return nil, ErrMissingAPIKey
+	}
+	hash := HashKey(plaintext)
+
+	a.mu.Lock()
+	cached, ok := a.cache[hash]
+	a.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.key, nil
+	}
+
+	key, err := a.repo.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		a.forget(hash)
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	a.mu.Lock()
+	a.cache[hash] = cachedKey{key: key, expires: time.Now().Add(keyCacheTTL)}
+	a.mu.Unlock()
+
+	// Recording usage once per cache refresh keeps last_used_at accurate to keyCacheTTL
+	// without a write per request
+	go func(id uint) {
+		touchCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := a.repo.TouchAPIKey(touchCtx, id, time.Now()); err != nil {
+			log.Printf("⚠️ Failed to record API key use: %v", err)
+		}
+	}(key.ID)
+
+	return key, nil
+}
+
+// forget drops a key hash from the cache
+func (a *Authenticator) forget(hash string) {
+	a.mu.Lock()
+	delete(a.cache, hash)
+	a.mu.Unlock()
+}
+
+// CreateKey creates a key with the given scopes and returns it with its plaintext,
+// which is not stored and cannot be retrieved again
+func (a *Authenticator) CreateKey(ctx context.Context, name string, scopes []string) (*models.APIKey, string, error) {
+	plaintext, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := a.storeKey(ctx, name, plaintext, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+// storeKey saves the hash of a plaintext key
+func (a *Authenticator) storeKey(ctx context.Context, name, plaintext string, scopes []string) (*models.APIKey, error) {
+	key := &models.APIKey{
+		Name:    name,
+		Prefix:  plaintext[:min(displayPrefixLength, len(plaintext))],
+		KeyHash: HashKey(plaintext),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if err := a.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, nil
+}
+
+// EnsureKey creates a key from a configured plaintext unless it already exists, and
+// returns the key it created (nil if it existed)
+// Used to bootstrap the first admin key from the environment
+func (a *Authenticator) EnsureKey(ctx context.Context, name, plaintext string, scopes []string) (*models.APIKey, error) {
+	_, err := a.repo.GetAPIKeyByHash(ctx, HashKey(plaintext))
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, err
+	}
+	return a.storeKey(ctx, name, plaintext, scopes)
+}
+
+// ListKeys returns every key, including revoked ones
+func (a *Authenticator) ListKeys(ctx context.Context) ([]models.APIKey, error) {
+	return a.repo.ListAPIKeys(ctx)
+}
+
+// RevokeKey revokes a key; this instance stops accepting it immediately
+func (a *Authenticator) RevokeKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	key, err := a.repo.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a.forget(key.KeyHash)
+	return key, nil
+}
+
+// Context keys carrying the authenticated key and player
+type (
+	contextKey       struct{}
+	playerContextKey struct{}
+)
+
+// WithKey attaches an authenticated key to ctx
+func WithKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// KeyFromContext returns the key attached to ctx, or nil
+func KeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(contextKey{}).(*models.APIKey)
+	return key
+}
+
+// WithPlayer attaches the username of a player authenticated by token to ctx
+func WithPlayer(ctx context.Context, player string) context.Context {
+	return context.WithValue(ctx, playerContextKey{}, player)
+}
+
+// PlayerFromContext returns the player attached to ctx, or ""
+func PlayerFromContext(ctx context.Context) string {
+	player, _ := ctx.Value(playerContextKey{}).(string)
+	return player
+}
+
+// CanViewPrivate reports whether the caller in ctx may see private data of username:
+// the player themselves or an admin key
+func CanViewPrivate(ctx context.Context, username string) bool {
+	if player := PlayerFromContext(ctx); player != "" && player == username {
+		return true
+	}
+	key := KeyFromContext(ctx)
+	return key != nil && key.HasScope(models.ScopeAdmin)
+}
+
+// KeyID returns the ID of a key, or 0 for no key (used to attribute writes)
+func KeyID(key *models.APIKey) uint {
+	if key == nil {
+		return 0
+	}
+	return key.ID
+}
+
+// ClientID identifies the authenticated client of a request: its API key, or the player
+// for player tokens; "" for anonymous requests
+func ClientID(key *models.APIKey, player string) string {
+	switch {
+	case key != nil:
+		return "key:" + strconv.FormatUint(uint64(key.ID), 10)
+	case player != "":
+		return "player:" + player
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Clock skew tolerated when checking exp, nbf and iat
+	tokenLeeway = 30 * time.Second
+
+	// An unknown kid reloads the JWKS file at most this often, picking up rotated keys
+	jwksReloadInterval = 10 * time.Second
+)
+
+var (
+	// ErrInvalidToken is returned for player tokens that fail verification
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrTokensDisabled is returned when a request carries a token but no JWT keys are configured
+	ErrTokensDisabled = errors.New("player tokens are not enabled")
+)
+
+// JWTConfig configures player token verification
+// HS256 uses a shared secret; RS256 uses the public keys of a JWKS file
+type JWTConfig struct {
+	HS256Secret string
+	JWKSFile    string
+	Issuer      string // Required iss claim (empty = not checked)
+	Audience    string // Required aud claim (empty = not checked)
+}
+
+// JWTVerifier verifies player tokens; the token subject is the player's username
+type JWTVerifier struct {
+	secret   []byte
+	jwksFile string
+	parser   *jwt.Parser
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey // kid -> key
+	lastAttempt time.Time                 // Last read of the JWKS file, successful or not
+}
+
+// NewJWTVerifier creates a verifier, or returns nil when neither HS256 nor RS256 is configured
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	if cfg.HS256Secret == "" && cfg.JWKSFile == "" {
+		return nil, nil
+	}
+
+	var methods []string
+	if cfg.HS256Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	v := &JWTVerifier{
+		jwksFile: cfg.JWKSFile,
+		parser:   jwt.NewParser(opts...),
+		keys:     map[string]*rsa.PublicKey{},
+	}
+	if cfg.HS256Secret != "" {
+		v.secret = []byte(cfg.HS256Secret)
+	}
+	if cfg.JWKSFile != "" {
+		v.lastAttempt = time.Now()
+		if err := v.loadJWKS(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Verify checks a token's signature and claims and returns its subject
+func (v *JWTVerifier) Verify(token string) (string, error) {
+	if v == nil {
+		return "", ErrTokensDisabled
+	}
+
+	parsed, err := v.parser.Parse(token, v.keyFor)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	subject, err := parsed.Claims.GetSubject()
+	if err != nil || subject == "" {
+		return "", fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return subject, nil
+}
+
+// keyFor returns the verification key for a token's algorithm and kid
+func (v *JWTVerifier) keyFor(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key := v.rsaKey(kid); key != nil {
+			return key, nil
+		}
+		// The key may have been rotated in since the file was last read
+		if v.reloadJWKS() {
+			if key := v.rsaKey(kid); key != nil {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// rsaKey looks up a key by kid; a token without kid matches a JWKS with a single key
+func (v *JWTVerifier) rsaKey(kid string) *rsa.PublicKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key
+		}
+	}
+	return v.keys[kid]
+}
+
+// reloadJWKS re-reads the JWKS file unless a read was attempted recently
+// Failed reads count too, so tokens with unknown kids cannot make every request re-read a
+// broken file
+func (v *JWTVerifier) reloadJWKS() bool {
+	v.mu.Lock()
+	recent := time.Since(v.lastAttempt) < jwksReloadInterval
+	if !recent {
+		v.lastAttempt = time.Now()
+	}
+	v.mu.Unlock()
+	if recent {
+		return false
+	}
+
+	if err := v.loadJWKS(); err != nil {
+		log.Printf("⚠️ Failed to reload JWKS: %v", err)
+		return false
+	}
+	return true
+}
+
+// jwks is a JSON Web Key Set (RFC 7517); only RSA signing keys are used
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS reads the RSA signing keys of the JWKS file
+func (v *JWTVerifier) loadJWKS() error {
+	data, err := os.ReadFile(v.jwksFile)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") || (jwk.Alg != "" && jwk.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return fmt.Errorf("invalid modulus for key %q: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return fmt.Errorf("invalid exponent for key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS file %s has no RS256 signing keys", v.jwksFile)
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// APIKeyHeader carries the API key
+	APIKeyHeader = "X-API-Key"
+
+	// Query parameters carrying credentials on GET requests from clients that cannot
+	// set headers (browser WebSocket and EventSource)
+	apiKeyQueryParam      = "api_key"
+	accessTokenQueryParam = "access_token"
+
+	// Locals storing the authenticated key and player; string keys so they are
+	// carried over to upgraded WebSocket connections
+	localsKey       = "api_key"
+	playerLocalsKey = "player"
+)
+
+// Require returns middleware that rejects requests without a key granting scope
+func (a *Authenticator) Require(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, err := a.Authenticate(c.UserContext(), keyFromRequest(c))
+		if err != nil {
+			return a.reject(c, err)
+		}
+		if !key.HasScope(scope) {
+			return forbidden(c, "API key lacks the "+scope+" scope")
+		}
+		setKey(c, key)
+		return c.Next()
+	}
+}
+
+// RequireKeyOrPlayer returns middleware accepting either a player token or a key granting
+// scope. A player token only authenticates the player: handlers must check that the
+// request concerns the player's own username.
+func (a *Authenticator) RequireKeyOrPlayer(scope string) fiber.Handler {
+	requireKey := a.Require(scope)
+	return func(c *fiber.Ctx) error {
+		token := tokenFromRequest(c)
+		if token == "" {
+			return requireKey(c)
+		}
+		if err := a.setPlayer(c, token); err != nil {
+			return a.reject(c, err)
+		}
+		return c.Next()
+	}
+}
+
+// RequireRead returns middleware for read endpoints. When reads require a key it
+// accepts a key with the read scope or a player token; otherwise it lets every request
+// through, identifying the caller if credentials are present
+func (a *Authenticator) RequireRead() fiber.Handler {
+	if a.requireReadKey {
+		return a.RequireKeyOrPlayer(models.ScopeRead)
+	}
+	return a.Identify()
+}
+
+// Identify returns middleware that authenticates whatever credentials a request carries
+// without requiring any. Invalid credentials are still rejected.
+func (a *Authenticator) Identify() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token := tokenFromRequest(c); token != "" {
+			if err := a.setPlayer(c, token); err != nil {
+				return a.reject(c, err)
+			}
+		}
+		if plaintext := keyFromRequest(c); plaintext != "" {
+			key, err := a.Authenticate(c.UserContext(), plaintext)
+			if err != nil {
+				return a.reject(c, err)
+			}
+			setKey(c, key)
+		}
+		return c.Next()
+	}
+}
+
+// setPlayer verifies a player token and records its subject on the request
+func (a *Authenticator) setPlayer(c *fiber.Ctx, token string) error {
+	player, err := a.jwt.Verify(token)
+	if err != nil {
+		return err
+	}
+	c.Locals(playerLocalsKey, player)
+	c.SetUserContext(WithPlayer(c.UserContext(), player))
+	return nil
+}
+
+// setKey records an authenticated key on the request
+func setKey(c *fiber.Ctx, key *models.APIKey) {
+	c.Locals(localsKey, key)
+	c.SetUserContext(WithKey(c.UserContext(), key))
+}
+
+// reject responds to a failed authentication
+func (a *Authenticator) reject(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrMissingAPIKey), errors.Is(err, ErrInvalidAPIKey),
+		errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTokensDisabled):
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: err.Error(),
+		})
+	default:
+		log.Printf("❌ API key check failed: %v", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+			Error:   "Authentication unavailable",
+			Message: "API keys cannot be verified right now",
+		})
+	}
+}
+
+// forbidden responds to an authenticated caller that is not allowed to do something
+func forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+		Error:   "Forbidden",
+		Message: message,
+	})
+}
+
+// KeyFromFiber returns the key authenticated for this request, or nil
+func KeyFromFiber(c *fiber.Ctx) *models.APIKey {
+	key, _ := c.Locals(localsKey).(*models.APIKey)
+	return key
+}
+
+// PlayerFromFiber returns the player authenticated by token for this request, or ""
+func PlayerFromFiber(c *fiber.Ctx) string {
+	player, _ := c.Locals(playerLocalsKey).(string)
+	return player
+}
+
+// FromLocals copies the identity recorded by the middleware into ctx, for handlers that
+// only see the locals (upgraded WebSocket connections)
+func FromLocals(ctx context.Context, locals func(key string) interface{}) context.Context {
+	if key, ok := locals(localsKey).(*models.APIKey); ok {
+		ctx = WithKey(ctx, key)
+	}
+	if player, ok := locals(playerLocalsKey).(string); ok {
+		ctx = WithPlayer(ctx, player)
+	}
+	return ctx
+}
+
+// keyFromRequest reads the key from the header, or from the query on GET requests
+func keyFromRequest(c *fiber.Ctx) string {
+	if key := c.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if c.Method() == fiber.MethodGet {
+		return c.Query(apiKeyQueryParam)
+	}
+	return ""
+}
+
+// tokenFromRequest reads a bearer token from Authorization, or from the query on GET requests
+func tokenFromRequest(c *fiber.Ctx) string {
+	if token := BearerToken(c.Get(fiber.HeaderAuthorization)); token != "" {
+		return token
+	}
+	if c.Method() == fiber.MethodGet {
+		return c.Query(accessTokenQueryParam)
+	}
+	return ""
+}
+
+// BearerToken extracts the token of an "Authorization: Bearer <token>" value
+func BearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/repository"
+)
+
+const (
+	// Headers (and gRPC metadata keys, lower-cased) carrying a submission signature
+	SignatureHeader          = "X-Signature"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+
+	// Nonces are 16-128 characters of [A-Za-z0-9_-]
+	minNonceLength = 16
+	maxNonceLength = 128
+)
+
+// Rejection reasons, reported per reason in the signature metrics
+const (
+	RejectMissing          = "missing"
+	RejectMalformed        = "malformed"
+	RejectExpired          = "expired"
+	RejectBadSignature     = "bad_signature"
+	RejectReplayed         = "replayed"
+	RejectNonceUnavailable = "nonce_store_unavailable"
+)
+
+var (
+	// ErrSignatureRejected is wrapped by every rejected submission signature
+	ErrSignatureRejected = errors.New("signature rejected")
+
+	// ErrNonceStoreUnavailable is returned when nonces cannot be checked (Redis down);
+	// submissions are refused rather than accepted without replay protection
+	ErrNonceStoreUnavailable = errors.New("nonce store unavailable")
+)
+
+// SignatureError is a rejected submission signature and the reason it was rejected
+type SignatureError struct {
+	Reason string
+	Detail string
+}
+
+func (e *SignatureError) Error() string {
+	return e.Detail
+}
+
+func (e *SignatureError) Unwrap() error {
+	return ErrSignatureRejected
+}
+
+// Signature is the signature of one score submission as sent by a game server
+type Signature struct {
+	Value     string // Hex HMAC-SHA256 of the canonical payload
+	Nonce     string
+	Timestamp string // Unix seconds
+}
+
+// SignatureVerifier checks HMAC-signed score submissions and rejects replays
+// The signed payload is "username\nrating\nnonce\ntimestamp"; a nonce is accepted once
+// within the skew window on either side of the server clock
+type SignatureVerifier struct {
+	secret []byte
+	skew   time.Duration
+	nonces *repository.RedisRepository
+
+	accepted atomic.Int64
+	rejected map[string]*atomic.Int64 // Reason -> count
+}
+
+// DefaultSignatureSkew is the clock skew used when none (or a non-positive one) is configured
+const DefaultSignatureSkew = 5 * time.Minute
+
+// NewSignatureVerifier creates a verifier, or returns nil when no secret is configured
+// A skew of zero or less would reject almost every submission and keep nonces forever,
+// so it falls back to DefaultSignatureSkew
+func NewSignatureVerifier(secret string, skew time.Duration, nonces *repository.RedisRepository) *SignatureVerifier {
+	if secret == "" {
+		return nil
+	}
+	if skew <= 0 {
+		log.Printf("⚠️ Invalid signature clock skew %s, using %s", skew, DefaultSignatureSkew)
+		skew = DefaultSignatureSkew
+	}
+
+	rejected := make(map[string]*atomic.Int64)
+	for _, reason := range []string{
+		RejectMissing, RejectMalformed, RejectExpired,
+		RejectBadSignature, RejectReplayed, RejectNonceUnavailable,
+	} {
+		rejected[reason] = new(atomic.Int64)
+	}
+
+	return &SignatureVerifier{
+		secret:   []byte(secret),
+		skew:     skew,
+		nonces:   nonces,
+		rejected: rejected,
+	}
+}
+
+// Skew returns the accepted difference between a signed timestamp and the server clock
+func (v *SignatureVerifier) Skew() time.Duration {
+	return v.skew
+}
+
+// Required reports whether a submission by the caller must be signed
+// Game servers calling with an API key hold the secret and must sign; players cannot hold
+// it, and their token already limits them to their own score
+func (v *SignatureVerifier) Required(player string) bool {
+	return v != nil && player == ""
+}
+
+// Verify checks the signature of a submission and claims its nonce
+// The nonce is only claimed once the signature is valid, so forged requests cannot
+// burn nonces of legitimate ones
+func (v *SignatureVerifier) Verify(ctx context.Context, username string, rating int, sig Signature) error {
+	if sig.Value == "" || sig.Nonce == "" || sig.Timestamp == "" {
+		return v.reject(RejectMissing, "signature, nonce and timestamp are required")
+	}
+	if !validNonce(sig.Nonce) {
+		return v.reject(RejectMalformed, "nonce must be 16-128 characters of [A-Za-z0-9_-]")
+	}
+
+	unix, err := strconv.ParseInt(sig.Timestamp, 10, 64)
+	if err != nil {
+		return v.reject(RejectMalformed, "timestamp must be unix seconds")
+	}
+	if drift := time.Since(time.Unix(unix, 0)); drift > v.skew || drift < -v.skew {
+		return v.reject(RejectExpired, "timestamp is outside the allowed clock skew")
+	}
+
+	given, err := hex.DecodeString(sig.Value)
+	if err != nil {
+		return v.reject(RejectMalformed, "signature must be hex encoded")
+	}
+	if !hmac.Equal(given, v.sign(username, rating, sig.Nonce, sig.Timestamp)) {
+		return v.reject(RejectBadSignature, "signature does not match payload")
+	}
+
+	// Nonces outlive the whole window in which their timestamp would be accepted
+	claimed, err := v.nonces.ClaimNonce(ctx, sig.Nonce, 2*v.skew)
+	if err != nil {
+		v.rejected[RejectNonceUnavailable].Add(1)
+		return ErrNonceStoreUnavailable
+	}
+	if !claimed {
+		return v.reject(RejectReplayed, "nonce has already been used")
+	}
+
+	v.accepted.Add(1)
+	return nil
+}
+
+// sign computes the HMAC of a submission's canonical payload
+func (v *SignatureVerifier) sign(username string, rating int, nonce, timestamp string) []byte {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(strings.Join([]string{username, strconv.Itoa(rating), nonce, timestamp}, "\n")))
+	return mac.Sum(nil)
+}
+
+// reject counts a rejection and returns it as a SignatureError
+func (v *SignatureVerifier) reject(reason, detail string) error {
+	v.rejected[reason].Add(1)
+	return &SignatureError{Reason: reason, Detail: detail}
+}
+
+// GetMetrics returns accepted and rejected submission counts, the latter per reason
+// A nil verifier reports signing as disabled
+func (v *SignatureVerifier) GetMetrics() map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	rejected := make(map[string]int64, len(v.rejected))
+	for reason, count := range v.rejected {
+		rejected[reason] = count.Load()
+	}
+
+	return map[string]interface{}{
+		"enabled":  true,
+		"accepted": v.accepted.Load(),
+		"rejected": rejected,
+	}
+}
+
+// validNonce reports whether a nonce has an acceptable length and alphabet
+// Excluding newlines keeps the canonical payload unambiguous
+func validNonce(nonce string) bool {
+	if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+		return false
+	}
+	for _, r := range nonce {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"backend/internal/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testSecret = "test-secret"
+
+func newTestVerifier(t *testing.T) (*SignatureVerifier, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return NewSignatureVerifier(testSecret, 5*time.Minute, repository.NewRedisRepository(client)), server
+}
+
+// signed returns a valid signature of a submission made at at
+func signed(v *SignatureVerifier, username string, rating int, nonce string, at time.Time) Signature {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return Signature{
+		Value:     hex.EncodeToString(v.sign(username, rating, nonce, timestamp)),
+		Nonce:     nonce,
+		Timestamp: timestamp,
+	}
+}
+
+func rejectReason(err error) string {
+	var sigErr *SignatureError
+	if errors.As(err, &sigErr) {
+		return sigErr.Reason
+	}
+	return ""
+}
+
+func TestVerifyClockSkew(t *testing.T) {
+	v, _ := newTestVerifier(t)
+	tests := []struct {
+		name   string
+		offset time.Duration
+		reason string
+	}{
+		{"now", 0, ""},
+		{"behind within skew", -4 * time.Minute, ""},
+		{"ahead within skew", 4 * time.Minute, ""},
+		{"behind past skew", -6 * time.Minute, RejectExpired},
+		{"ahead past skew", 6 * time.Minute, RejectExpired},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := "nonce-clock-skew-" + strconv.Itoa(i)
+			err := v.Verify(context.Background(), "alice", 1500, signed(v, "alice", 1500, nonce, time.Now().Add(tt.offset)))
+			if got := rejectReason(err); got != tt.reason || (tt.reason == "" && err != nil) {
+				t.Errorf("err = %v, want reason %q", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSkewFallback(t *testing.T) {
+	for _, skew := range []time.Duration{0, -time.Second} {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		v := NewSignatureVerifier(testSecret, skew, repository.NewRedisRepository(client))
+		if v.Skew() != DefaultSignatureSkew {
+			t.Fatalf("skew %s: using %s, want %s", skew, v.Skew(), DefaultSignatureSkew)
+		}
+		if err := v.Verify(context.Background(), "alice", 1500, signed(v, "alice", 1500, "nonce-skew-fallback", time.Now())); err != nil {
+			t.Fatalf("skew %s: %v", skew, err)
+		}
+		// Nonces must expire, or the store grows forever
+		if ttl := server.TTL("leaderboard:nonce:nonce-skew-fallback"); ttl != 2*DefaultSignatureSkew {
+			t.Fatalf("skew %s: nonce TTL %s, want %s", skew, ttl, 2*DefaultSignatureSkew)
+		}
+	}
+}
+
+func TestVerifyReplayedNonce(t *testing.T) {
+	v, _ := newTestVerifier(t)
+	sig := signed(v, "alice", 1500, "nonce-replayed-0001", time.Now())
+
+	if err := v.Verify(context.Background(), "alice", 1500, sig); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	err := v.Verify(context.Background(), "alice", 1500, sig)
+	if rejectReason(err) != RejectReplayed {
+		t.Fatalf("replay: err = %v, want %q", err, RejectReplayed)
+	}
+}
+
+func TestVerifyTamperedRating(t *testing.T) {
+	v, _ := newTestVerifier(t)
+	sig := signed(v, "alice", 1500, "nonce-tampered-001", time.Now())
+
+	err := v.Verify(context.Background(), "alice", 9500, sig)
+	if rejectReason(err) != RejectBadSignature {
+		t.Fatalf("tampered: err = %v, want %q", err, RejectBadSignature)
+	}
+	// A forged request must not burn the nonce of the genuine one
+	if err := v.Verify(context.Background(), "alice", 1500, sig); err != nil {
+		t.Fatalf("genuine after forgery: %v", err)
+	}
+}
+
+func TestVerifyNonceStoreDown(t *testing.T) {
+	v, server := newTestVerifier(t)
+	server.Close()
+
+	err := v.Verify(context.Background(), "alice", 1500, signed(v, "alice", 1500, "nonce-store-down-1", time.Now()))
+	if !errors.Is(err, ErrNonceStoreUnavailable) {
+		t.Fatalf("err = %v, want ErrNonceStoreUnavailable", err)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	v, _ := newTestVerifier(t)
+	if !v.Required("") {
+		t.Error("API-key submissions must be signed")
+	}
+	if v.Required("alice") {
+		t.Error("player submissions must not need a signature")
+	}
+	var disabled *SignatureVerifier
+	if disabled.Required("") {
+		t.Error("nothing is signed while signing is disabled")
+	}
+}
@@ -0,0 +1,252 @@
+package breaker
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned without calling the guarded operation while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the current state of a circuit breaker
+type State int
+
+const (
+	// StateClosed lets all calls through and counts consecutive failures
+	StateClosed State = iota
+
+	// StateOpen rejects all calls until the open timeout elapses
+	StateOpen
+
+	// StateHalfOpen lets a limited number of probe calls through to test recovery
+	StateHalfOpen
+)
+
+// String returns the lowercase name of the state
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Config holds circuit breaker tuning
+type Config struct {
+	FailureThreshold int           // Consecutive failures before the breaker opens
+	OpenTimeout      time.Duration // Time spent open before half-open probing starts
+	HalfOpenProbes   int           // Concurrent probe calls allowed while half-open
+}
+
+// DefaultConfig returns the breaker configuration used when none is supplied
+func DefaultConfig() Config {
+	return Config{
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// Snapshot is a point-in-time view of a breaker, used for health reporting
+type Snapshot struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalFailures       int64      `json:"total_failures"`
+	Rejected            int64      `json:"rejected"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Breaker is a consecutive-failure circuit breaker with half-open probing
+type Breaker struct {
+	name      string
+	cfg       Config
+	isFailure func(error) bool
+
+	mu             sync.Mutex
+	state          State
+	generation     uint64 // Bumped on every state change, so late outcomes can be told apart
+	failures       int
+	probesInFlight int
+	openedAt       time.Time
+	totalFailures  int64
+	rejected       int64
+	lastError      string
+
+	now func() time.Time // Clock, replaced in tests
+}
+
+// admission is a call let through by allow: the generation it was admitted in and
+// whether it is a half-open probe
+type admission struct {
+	generation uint64
+	probe      bool
+}
+
+// New creates a breaker in the closed state
+// isFailure decides which errors count towards tripping; nil counts every error
+func New(name string, cfg Config, isFailure func(error) bool) *Breaker {
+	defaults := DefaultConfig()
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaults.OpenTimeout
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+	if isFailure == nil {
+		isFailure = func(err error) bool { return true }
+	}
+
+	return &Breaker{
+		name:      name,
+		cfg:       cfg,
+		isFailure: isFailure,
+		state:     StateClosed,
+		now:       time.Now,
+	}
+}
+
+// Execute runs fn if the breaker allows it and records the outcome
+// Returns ErrOpen without calling fn while the breaker is open
+func (b *Breaker) Execute(fn func() error) error {
+	call, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	b.record(call, err)
+	return err
+}
+
+// State returns the current breaker state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refreshLocked()
+	return b.state
+}
+
+// Snapshot returns the current breaker state and counters
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refreshLocked()
+
+	snap := Snapshot{
+		State:               b.state.String(),
+		ConsecutiveFailures: b.failures,
+		TotalFailures:       b.totalFailures,
+		Rejected:            b.rejected,
+		LastError:           b.lastError,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		snap.OpenedAt = &openedAt
+	}
+	return snap
+}
+
+// allow decides whether a call may proceed, and stamps it with the current generation
+func (b *Breaker) allow() (admission, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refreshLocked()
+
+	switch b.state {
+	case StateOpen:
+		b.rejected++
+		return admission{}, ErrOpen
+
+	case StateHalfOpen:
+		if b.probesInFlight >= b.cfg.HalfOpenProbes {
+			b.rejected++
+			return admission{}, ErrOpen
+		}
+		b.probesInFlight++
+		return admission{generation: b.generation, probe: true}, nil
+
+	default:
+		return admission{generation: b.generation}, nil
+	}
+}
+
+// record updates the breaker with the outcome of a call
+func (b *Breaker) record(call admission, err error) {
+	failed := err != nil && b.isFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failed {
+		b.totalFailures++
+		b.lastError = err.Error()
+	}
+
+	// Calls admitted before the last state change, such as a slow probe from an earlier
+	// half-open period, no longer say anything about the current state
+	if call.generation != b.generation {
+		return
+	}
+
+	if call.probe {
+		b.probesInFlight--
+		if failed {
+			b.openLocked()
+		} else {
+			b.closeLocked()
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold {
+		b.openLocked()
+	}
+}
+
+// refreshLocked moves an open breaker to half-open once the open timeout has elapsed
+func (b *Breaker) refreshLocked() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = StateHalfOpen
+		b.generation++
+		b.probesInFlight = 0
+		log.Printf("🟡 Circuit breaker [%s] half-open, probing", b.name)
+	}
+}
+
+// openLocked trips the breaker
+func (b *Breaker) openLocked() {
+	if b.state != StateOpen {
+		log.Printf("🔴 Circuit breaker [%s] opened: %s", b.name, b.lastError)
+	}
+	b.state = StateOpen
+	b.generation++
+	b.openedAt = b.now()
+}
+
+// closeLocked resets the breaker after a successful probe
+func (b *Breaker) closeLocked() {
+	if b.state != StateClosed {
+		log.Printf("🟢 Circuit breaker [%s] closed", b.name)
+	}
+	b.state = StateClosed
+	b.generation++
+	b.failures = 0
+	b.probesInFlight = 0
+}
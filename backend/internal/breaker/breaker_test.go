@@ -0,0 +1,160 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errDown     = errors.New("connection refused")
+	errNotFound = errors.New("not found")
+)
+
+// newTestBreaker returns a breaker on a manual clock, advanced by the returned function
+func newTestBreaker(probes int) (*Breaker, func(time.Duration)) {
+	b := New("test", Config{FailureThreshold: 3, OpenTimeout: time.Second, HalfOpenProbes: probes},
+		func(err error) bool { return !errors.Is(err, errNotFound) })
+	now := time.Unix(0, 0)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+// trip opens the breaker with threshold failures
+func trip(b *Breaker) {
+	for i := 0; i < 3; i++ {
+		b.Execute(func() error { return errDown })
+	}
+}
+
+func TestBreakerStateMachine(t *testing.T) {
+	// Steps: "ok", "fail" and "miss" (an error that isn't a failure) run a call,
+	// "wait" lets the open timeout elapse
+	tests := []struct {
+		name  string
+		steps []string
+		want  State
+	}{
+		{"starts closed", nil, StateClosed},
+		{"below threshold", []string{"fail", "fail"}, StateClosed},
+		{"opens at threshold", []string{"fail", "fail", "fail"}, StateOpen},
+		{"success resets the count", []string{"fail", "fail", "ok", "fail", "fail"}, StateClosed},
+		{"non-failures don't count", []string{"fail", "miss", "fail", "miss"}, StateClosed},
+		{"half-open after timeout", []string{"fail", "fail", "fail", "wait"}, StateHalfOpen},
+		{"probe success closes", []string{"fail", "fail", "fail", "wait", "ok"}, StateClosed},
+		{"probe failure reopens", []string{"fail", "fail", "fail", "wait", "fail"}, StateOpen},
+		{"reopened breaker waits again", []string{"fail", "fail", "fail", "wait", "fail", "wait"}, StateHalfOpen},
+		{"closed again counts from zero", []string{"fail", "fail", "fail", "wait", "ok", "fail", "fail"}, StateClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, advance := newTestBreaker(1)
+			for _, step := range tt.steps {
+				switch step {
+				case "ok":
+					b.Execute(func() error { return nil })
+				case "fail":
+					b.Execute(func() error { return errDown })
+				case "miss":
+					b.Execute(func() error { return errNotFound })
+				case "wait":
+					advance(time.Second)
+				}
+			}
+			if got := b.State(); got != tt.want {
+				t.Fatalf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerRejectsWhileOpen(t *testing.T) {
+	b, advance := newTestBreaker(1)
+	trip(b)
+
+	called := false
+	if err := b.Execute(func() error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker: err %v, called %v", err, called)
+	}
+
+	// Only one probe at a time while half-open
+	advance(time.Second)
+	probe, err := b.allow()
+	if err != nil || !probe.probe {
+		t.Fatalf("first probe: %+v, err %v", probe, err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second probe: err %v, want ErrOpen", err)
+	}
+	b.record(probe, nil)
+
+	if snap := b.Snapshot(); snap.State != "closed" || snap.Rejected != 2 || snap.TotalFailures != 3 {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+}
+
+func TestBreakerIgnoresLateOutcomes(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(b *Breaker, advance func(time.Duration))
+		want State
+	}{
+		{
+			// A probe from the first half-open period succeeds after a second one began
+			name: "late probe success",
+			run: func(b *Breaker, advance func(time.Duration)) {
+				trip(b)
+				advance(time.Second)
+				slow, _ := b.allow()
+				fast, _ := b.allow()
+				b.record(fast, errDown)
+				advance(time.Second)
+				b.State() // Half-open again
+				b.record(slow, nil)
+			},
+			want: StateHalfOpen,
+		},
+		{
+			// A probe fails after another probe of the same period closed the breaker
+			name: "late probe failure",
+			run: func(b *Breaker, advance func(time.Duration)) {
+				trip(b)
+				advance(time.Second)
+				slow, _ := b.allow()
+				fast, _ := b.allow()
+				b.record(fast, nil)
+				b.record(slow, errDown)
+			},
+			want: StateClosed,
+		},
+		{
+			// Calls admitted before the breaker opened fail after it closed again
+			name: "late closed-period failures",
+			run: func(b *Breaker, advance func(time.Duration)) {
+				var slow []admission
+				for i := 0; i < 3; i++ {
+					call, _ := b.allow()
+					slow = append(slow, call)
+				}
+				trip(b)
+				advance(time.Second)
+				b.Execute(func() error { return nil })
+				for _, call := range slow {
+					b.record(call, errDown)
+				}
+			},
+			want: StateClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, advance := newTestBreaker(2)
+			tt.run(b, advance)
+			if got := b.State(); got != tt.want {
+				t.Fatalf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,11 @@ type Config struct {
 	Database DatabaseConfig
 	Redis    RedisConfig
 	Server   ServerConfig
+	Breaker  BreakerConfig
+	Hub      HubConfig
+	Auth     AuthConfig
+	Limits   RateLimitConfig
+	Cheat    AntiCheatConfig
 }
 
 // DatabaseConfig holds database configuration
@@ -38,7 +44,62 @@ type RedisConfig struct {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port int
+	Port     int
+	GRPCPort int // 0 disables the gRPC server
+
+	IdempotencyTTLSec int // How long responses are kept for replay to requests with the same Idempotency-Key
+
+	// Reverse proxies; the client IP is read from ProxyHeader only on requests they forward
+	TrustedProxies string // Comma-separated IPs or CIDRs (empty = trust no proxy, use the peer address)
+	ProxyHeader    string // Header the proxies set to the client IP
+}
+
+// BreakerConfig holds circuit breaker configuration shared by Redis and PostgreSQL
+type BreakerConfig struct {
+	FailureThreshold int
+	OpenTimeoutSec   int
+	HalfOpenProbes   int
+}
+
+// HubConfig holds WebSocket hub configuration
+type HubConfig struct {
+	Shards int // Client shards, each with its own broadcast goroutine (0 = one per CPU)
+}
+
+// AuthConfig holds API key, player token and CORS configuration
+type AuthConfig struct {
+	AdminAPIKey    string // Bootstraps an admin key with this value if it does not exist yet
+	RequireReadKey bool   // Require a key with the read scope (or a player token) for read endpoints
+	CORSOrigins    string // Comma-separated origins allowed to call the API from a browser
+
+	// Player tokens (JWT); disabled when neither a secret nor a JWKS file is set
+	JWTSecret   string // HS256 shared secret
+	JWTJWKSFile string // Local JWKS file with the RS256 public keys
+	JWTIssuer   string // Required iss claim (optional)
+	JWTAudience string // Required aud claim (optional)
+
+	// Signed score submissions; disabled when no secret is set
+	SigningSecret  string // HMAC-SHA256 secret shared with game servers
+	SigningSkewSec int    // Accepted difference between the signed timestamp and the server clock (<= 0 uses 300)
+}
+
+// RateLimitConfig holds the rate limit rules of each route
+// Rules are comma-separated <dimension>:<limit>/<period>, dimension being key, ip or username
+type RateLimitConfig struct {
+	Scores string // POST /api/v1/scores and gRPC UpdateScore
+	Reads  string // Read endpoints, streams and GraphQL
+}
+
+// AntiCheatConfig holds the anti-cheat rules applied to score submissions
+// A limit of 0 disables its rule; actions are "flag" (apply, queue for review) or
+// "block" (hold for review)
+type AntiCheatConfig struct {
+	MaxJump                int // Largest rating change allowed in one update
+	MaxJumpAction          string
+	MaxUpdatesPerMinute    int // Most updates one user may receive per minute
+	MaxUpdatesAction       string
+	MaxChangePerHour       int // Largest rating change allowed within an hour across updates
+	MaxChangePerHourAction string
 }
 
 // Load loads configuration from environment variables
@@ -69,7 +130,43 @@ func Load() (*Config, error) {
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
 		Server: ServerConfig{
-			Port: getEnvAsInt("BACKEND_PORT", 8000),
+			Port:     getEnvAsInt("BACKEND_PORT", 8000),
+			GRPCPort: getEnvAsInt("GRPC_PORT", 9000),
+
+			IdempotencyTTLSec: getEnvAsInt("IDEMPOTENCY_TTL_SEC", 86400),
+			TrustedProxies:    getEnv("TRUSTED_PROXIES", ""),
+			ProxyHeader:       getEnv("PROXY_HEADER", "X-Forwarded-For"),
+		},
+		Breaker: BreakerConfig{
+			FailureThreshold: getEnvAsInt("BREAKER_FAILURE_THRESHOLD", 5),
+			OpenTimeoutSec:   getEnvAsInt("BREAKER_OPEN_TIMEOUT_SEC", 10),
+			HalfOpenProbes:   getEnvAsInt("BREAKER_HALF_OPEN_PROBES", 1),
+		},
+		Hub: HubConfig{
+			Shards: getEnvAsInt("HUB_SHARDS", 0),
+		},
+		Auth: AuthConfig{
+			AdminAPIKey:    getEnv("ADMIN_API_KEY", ""),
+			RequireReadKey: getEnvAsBool("REQUIRE_READ_KEY", false),
+			CORSOrigins:    getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:8081,http://localhost:19006"),
+			JWTSecret:      getEnv("JWT_HS256_SECRET", ""),
+			JWTJWKSFile:    getEnv("JWT_JWKS_FILE", ""),
+			JWTIssuer:      getEnv("JWT_ISSUER", ""),
+			JWTAudience:    getEnv("JWT_AUDIENCE", ""),
+			SigningSecret:  getEnv("SCORE_SIGNING_SECRET", ""),
+			SigningSkewSec: getEnvAsInt("SCORE_SIGNING_SKEW_SEC", 300),
+		},
+		Cheat: AntiCheatConfig{
+			MaxJump:                getEnvAsInt("ANTICHEAT_MAX_JUMP", 1000),
+			MaxJumpAction:          getEnv("ANTICHEAT_MAX_JUMP_ACTION", "block"),
+			MaxUpdatesPerMinute:    getEnvAsInt("ANTICHEAT_MAX_UPDATES_PER_MIN", 30),
+			MaxUpdatesAction:       getEnv("ANTICHEAT_MAX_UPDATES_ACTION", "flag"),
+			MaxChangePerHour:       getEnvAsInt("ANTICHEAT_MAX_CHANGE_PER_HOUR", 2000),
+			MaxChangePerHourAction: getEnv("ANTICHEAT_MAX_CHANGE_PER_HOUR_ACTION", "flag"),
+		},
+		Limits: RateLimitConfig{
+			Scores: getEnv("RATE_LIMIT_SCORES", "key:50/1s,ip:50/1s,username:5/1s"),
+			Reads:  getEnv("RATE_LIMIT_READS", "key:100/1s,ip:100/1s"),
 		},
 	}
 
@@ -92,6 +189,17 @@ func (c *Config) GetDSN() string {
 	)
 }
 
+// GetTrustedProxies returns the trusted reverse proxies, or nil when none are configured
+func (c *Config) GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.Server.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 // GetRedisAddr returns the Redis address
 func (c *Config) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Redis.Host, c.Redis.Port)
@@ -113,3 +221,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsBool retrieves an environment variable as a boolean or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
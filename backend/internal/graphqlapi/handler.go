@@ -0,0 +1,100 @@
+// Package graphqlapi serves the leaderboard as GraphQL at /graphql: queries over HTTP,
+// subscriptions over WebSocket (graphql-transport-ws), both resolved through the
+// LeaderboardService and the WebSocket hub
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/internal/websocket"
+
+	"github.com/gofiber/fiber/v2"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// Deepest selection accepted, enough for board -> entries -> user -> neighbours -> user
+	maxQueryDepth = 10
+
+	// Resolvers run in parallel up to this many per operation
+	maxParallelism = 20
+
+	// Longest a query may run
+	queryTimeout = 10 * time.Second
+)
+
+// Handler serves GraphQL queries and subscriptions
+type Handler struct {
+	schema  *graphql.Schema
+	service *service.LeaderboardService
+}
+
+// NewHandler parses the schema and binds it to the service and hub
+func NewHandler(service *service.LeaderboardService, hub *websocket.Hub) *Handler {
+	resolver := &Resolver{service: service, hub: hub}
+	return &Handler{
+		schema: graphql.MustParseSchema(schemaSDL, resolver,
+			graphql.MaxDepth(maxQueryDepth),
+			graphql.MaxParallelism(maxParallelism),
+		),
+		service: service,
+	}
+}
+
+// request is a GraphQL operation as sent over HTTP or in a subscribe message
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query executes a GraphQL query
+// @Summary Execute a GraphQL query
+// @Description Accepts {query, operationName, variables} as a JSON body (POST) or query parameters (GET)
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Success 200 {object} graphql.Response
+// @Failure 400 {object} models.ErrorResponse
+// @Router /graphql [post]
+func (h *Handler) Query(c *fiber.Ctx) error {
+	var req request
+	if c.Method() == fiber.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+					Error:   "Invalid variables",
+					Message: err.Error(),
+				})
+			}
+		}
+	} else if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request body",
+			Message: err.Error(),
+		})
+	}
+
+	if req.Query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "Missing query",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), queryTimeout)
+	defer cancel()
+	ctx = withLoaders(ctx, newLoaders(h.service, true))
+
+	// GraphQL reports resolver errors in the response body, so this is always 200
+	return c.JSON(h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
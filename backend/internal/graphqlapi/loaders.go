@@ -0,0 +1,143 @@
+package graphqlapi
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/service"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait is how long a loader collects keys before fetching them as one batch
+// Sibling fields resolve in parallel, so a page of entries lands in a single batch
+const loaderWait = 2 * time.Millisecond
+
+// historyKey selects a user's history; the same limit is batched into one query
+type historyKey struct {
+	username string
+	limit    int
+}
+
+// loaders batch per-user lookups made while resolving one operation, so a page of
+// N users costs one HMGET for ratings, one pipeline for ranks and one query per
+// history limit instead of N of each
+type loaders struct {
+	ratings *dataloader.Loader[string, *int]
+	entries *dataloader.Loader[string, *models.LeaderboardEntry]
+	history *dataloader.Loader[historyKey, []models.ScoreEvent]
+}
+
+type loadersKey struct{}
+
+// newLoaders creates the loaders for one operation
+// Queries cache results for their lifetime; subscriptions run indefinitely, so
+// their loaders only batch and every event sees fresh values
+func newLoaders(svc *service.LeaderboardService, cache bool) *loaders {
+	ratingOpts := []dataloader.Option[string, *int]{dataloader.WithWait[string, *int](loaderWait)}
+	entryOpts := []dataloader.Option[string, *models.LeaderboardEntry]{dataloader.WithWait[string, *models.LeaderboardEntry](loaderWait)}
+	historyOpts := []dataloader.Option[historyKey, []models.ScoreEvent]{dataloader.WithWait[historyKey, []models.ScoreEvent](loaderWait)}
+	if !cache {
+		ratingOpts = append(ratingOpts, dataloader.WithCache[string, *int](&dataloader.NoCache[string, *int]{}))
+		entryOpts = append(entryOpts, dataloader.WithCache[string, *models.LeaderboardEntry](&dataloader.NoCache[string, *models.LeaderboardEntry]{}))
+		historyOpts = append(historyOpts, dataloader.WithCache[historyKey, []models.ScoreEvent](&dataloader.NoCache[historyKey, []models.ScoreEvent]{}))
+	}
+
+	return &loaders{
+		ratings: dataloader.NewBatchedLoader(batchRatings(svc), ratingOpts...),
+		entries: dataloader.NewBatchedLoader(batchEntries(svc), entryOpts...),
+		history: dataloader.NewBatchedLoader(batchHistory(svc), historyOpts...),
+	}
+}
+
+// withLoaders attaches loaders to an operation's context
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+// loadersFrom returns the loaders of the operation being resolved
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// batchRatings resolves ratings with GetUserScoreBatch (one HMGET)
+// Users not on the leaderboard resolve to nil
+func batchRatings(svc *service.LeaderboardService) dataloader.BatchFunc[string, *int] {
+	return func(ctx context.Context, usernames []string) []*dataloader.Result[*int] {
+		results := make([]*dataloader.Result[*int], len(usernames))
+		ratings, err := svc.GetUserRatings(ctx, usernames)
+		for i, username := range usernames {
+			if err != nil {
+				results[i] = &dataloader.Result[*int]{Error: err}
+				continue
+			}
+			result := &dataloader.Result[*int]{}
+			if rating, ok := ratings[username]; ok {
+				result.Data = &rating
+			}
+			results[i] = result
+		}
+		return results
+	}
+}
+
+// batchEntries resolves ranks and ratings with GetUserEntriesFor, so a shadow-banned
+// player still finds themselves
+// Users not on the leaderboard resolve to nil
+func batchEntries(svc *service.LeaderboardService) dataloader.BatchFunc[string, *models.LeaderboardEntry] {
+	return func(ctx context.Context, usernames []string) []*dataloader.Result[*models.LeaderboardEntry] {
+		results := make([]*dataloader.Result[*models.LeaderboardEntry], len(usernames))
+		entries, err := svc.GetUserEntriesFor(ctx, auth.PlayerFromContext(ctx), usernames)
+		for i, username := range usernames {
+			if err != nil {
+				results[i] = &dataloader.Result[*models.LeaderboardEntry]{Error: err}
+				continue
+			}
+			result := &dataloader.Result[*models.LeaderboardEntry]{}
+			if entry, ok := entries[username]; ok {
+				result.Data = &entry
+			}
+			results[i] = result
+		}
+		return results
+	}
+}
+
+// batchHistory resolves score history with one query per distinct limit
+func batchHistory(svc *service.LeaderboardService) dataloader.BatchFunc[historyKey, []models.ScoreEvent] {
+	return func(ctx context.Context, keys []historyKey) []*dataloader.Result[[]models.ScoreEvent] {
+		byLimit := make(map[int][]string)
+		for _, key := range keys {
+			byLimit[key.limit] = append(byLimit[key.limit], key.username)
+		}
+
+		history := make(map[historyKey][]models.ScoreEvent, len(keys))
+		errs := make(map[int]error)
+		for limit, usernames := range byLimit {
+			events, err := svc.GetScoreHistory(ctx, usernames, limit)
+			if err != nil {
+				errs[limit] = err
+				continue
+			}
+			for username, userEvents := range events {
+				history[historyKey{username: username, limit: limit}] = userEvents
+			}
+		}
+
+		results := make([]*dataloader.Result[[]models.ScoreEvent], len(keys))
+		for i, key := range keys {
+			if err := errs[key.limit]; err != nil {
+				results[i] = &dataloader.Result[[]models.ScoreEvent]{Error: err}
+				continue
+			}
+			events := history[key]
+			if events == nil {
+				events = []models.ScoreEvent{}
+			}
+			results[i] = &dataloader.Result[[]models.ScoreEvent]{Data: events}
+		}
+		return results
+	}
+}
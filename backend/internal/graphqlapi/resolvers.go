@@ -0,0 +1,394 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/internal/websocket"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// Caps for list arguments (defaults are declared in the schema), matching the REST
+// API's pagination limit
+const (
+	maxBoardLimit      = 100
+	maxUsersPerQuery   = 100
+	maxHistoryLimit    = 100
+	maxNeighbourRadius = 10
+)
+
+// Int64 is the GraphQL scalar for leaderboard versions
+type Int64 int64
+
+// ImplementsGraphQLType maps Int64 to the schema scalar of the same name
+func (Int64) ImplementsGraphQLType(name string) bool {
+	return name == "Int64"
+}
+
+// UnmarshalGraphQL accepts integer literals and variables (numbers or numeric strings)
+func (v *Int64) UnmarshalGraphQL(input interface{}) error {
+	switch value := input.(type) {
+	case int32:
+		*v = Int64(value)
+	case int64:
+		*v = Int64(value)
+	case float64:
+		if value != float64(int64(value)) {
+			return fmt.Errorf("Int64 must be an integer, got %v", value)
+		}
+		*v = Int64(value)
+	case string:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Int64 %q", value)
+		}
+		*v = Int64(parsed)
+	default:
+		return fmt.Errorf("invalid Int64 type %T", input)
+	}
+	return nil
+}
+
+// MarshalJSON encodes Int64 as a JSON number
+func (v Int64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(v))
+}
+
+// Resolver is the root resolver, backed by the same service and hub as the HTTP API
+type Resolver struct {
+	service *service.LeaderboardService
+	hub     *websocket.Hub
+}
+
+// Board resolves a leaderboard page
+func (r *Resolver) Board(ctx context.Context, args struct {
+	Offset int32
+	Limit  int32
+}) (*boardResolver, error) {
+	offset, limit := int(args.Offset), int(args.Limit)
+	if offset < 0 {
+		return nil, errors.New("offset must be non-negative")
+	}
+	if limit < 1 || limit > maxBoardLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxBoardLimit)
+	}
+
+	// Read the version first so subscribing from it never misses a change to the page
+	version := r.hub.Version()
+	// Shadow-banned players still see themselves, as on GET /api/v1/leaderboard
+	page, err := r.service.GetLeaderboardFor(ctx, auth.PlayerFromContext(ctx), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &boardResolver{service: r.service, page: page, version: version, degraded: r.service.IsDegraded()}, nil
+}
+
+// User resolves one user, or null if they are not on the leaderboard
+func (r *Resolver) User(ctx context.Context, args struct{ Username string }) (*userResolver, error) {
+	entry, err := loadersFrom(ctx).entries.Load(ctx, args.Username)()
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	return &userResolver{service: r.service, username: args.Username}, nil
+}
+
+// Users resolves several users in one batch, null for users not on the leaderboard
+func (r *Resolver) Users(ctx context.Context, args struct{ Usernames []string }) ([]*userResolver, error) {
+	if len(args.Usernames) > maxUsersPerQuery {
+		return nil, fmt.Errorf("at most %d usernames per query", maxUsersPerQuery)
+	}
+
+	entries, errs := loadersFrom(ctx).entries.LoadMany(ctx, args.Usernames)()
+	users := make([]*userResolver, len(args.Usernames))
+	for i, username := range args.Usernames {
+		if errs != nil && errs[i] != nil {
+			return nil, errs[i]
+		}
+		if entries[i] != nil {
+			users[i] = &userResolver{service: r.service, username: username}
+		}
+	}
+	return users, nil
+}
+
+// LeaderboardChanged streams leaderboard changes from the hub, starting with a DELTA of
+// the changes missed since lastVersion (or RESYNC when they can no longer be replayed)
+func (r *Resolver) LeaderboardChanged(ctx context.Context, args struct{ LastVersion Int64 }) (<-chan *leaderboardChangeResolver, error) {
+	lastVersion := int64(args.LastVersion)
+	if lastVersion < 0 {
+		return nil, errors.New("lastVersion must be non-negative")
+	}
+
+	stream := r.hub.OpenStream(websocket.TransportGraphQL, websocket.ConnectOptions{LastVersion: lastVersion})
+	return forward(ctx, stream, func(event websocket.Event) (*leaderboardChangeResolver, bool) {
+		return toLeaderboardChange(r.service, event)
+	}), nil
+}
+
+// RankChanged streams rank changes of one user, using the hub's rank watches
+func (r *Resolver) RankChanged(ctx context.Context, args struct {
+	Username string
+	TopN     int32
+}) (<-chan *rankChangeResolver, error) {
+	stream := r.hub.OpenStream(websocket.TransportGraphQL, websocket.ConnectOptions{})
+	if err := stream.Watch(args.Username, int(args.TopN)); err != nil {
+		stream.Close()
+		return nil, err
+	}
+	return forward(ctx, stream, func(event websocket.Event) (*rankChangeResolver, bool) {
+		message, ok := event.Message.(websocket.RankChanged)
+		if !ok || message.Username != args.Username {
+			return nil, false
+		}
+		return &rankChangeResolver{service: r.service, message: message}, true
+	}), nil
+}
+
+// forward relays the hub events that convert to T until ctx is done or the hub drops
+// the stream, which completes the subscription
+func forward[T any](ctx context.Context, stream *websocket.Stream, convert func(websocket.Event) (T, bool)) <-chan T {
+	results := make(chan T)
+	go func() {
+		defer close(results)
+		defer stream.Close()
+
+		for {
+			event, err := stream.Next(ctx)
+			if err != nil {
+				if errors.Is(err, websocket.ErrSlowConsumer) {
+					log.Printf("🐢 GraphQL subscription evicted as a slow consumer")
+				}
+				return
+			}
+
+			result, ok := convert(event)
+			if !ok {
+				continue // Not relevant to this subscription (e.g. presence)
+			}
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// boardResolver resolves Board
+type boardResolver struct {
+	service  *service.LeaderboardService
+	page     *models.LeaderboardResponse
+	version  int64
+	degraded bool
+}
+
+func (b *boardResolver) Version() Int64 { return Int64(b.version) }
+func (b *boardResolver) Total() int32   { return int32(b.page.Total) }
+func (b *boardResolver) Offset() int32  { return int32(b.page.Offset) }
+func (b *boardResolver) Limit() int32   { return int32(b.page.Limit) }
+func (b *boardResolver) Degraded() bool { return b.degraded }
+
+func (b *boardResolver) Entries(ctx context.Context) []*entryResolver {
+	return newEntryResolvers(ctx, b.service, b.page.Data)
+}
+
+// newEntryResolvers wraps leaderboard entries, priming the rating loader with the
+// ratings already read so User.rating costs nothing for them
+func newEntryResolvers(ctx context.Context, svc *service.LeaderboardService, entries []models.LeaderboardEntry) []*entryResolver {
+	l := loadersFrom(ctx)
+	resolvers := make([]*entryResolver, len(entries))
+	for i, entry := range entries {
+		rating := entry.Rating
+		l.ratings.Prime(ctx, entry.Username, &rating)
+		resolvers[i] = &entryResolver{service: svc, entry: entry}
+	}
+	return resolvers
+}
+
+// entryResolver resolves Entry
+type entryResolver struct {
+	service *service.LeaderboardService
+	entry   models.LeaderboardEntry
+}
+
+func (e *entryResolver) Rank() int32   { return int32(e.entry.Rank) }
+func (e *entryResolver) Rating() int32 { return int32(e.entry.Rating) }
+
+func (e *entryResolver) User() *userResolver {
+	return &userResolver{service: e.service, username: e.entry.Username}
+}
+
+// userResolver resolves User; every field except username goes through the loaders
+type userResolver struct {
+	service  *service.LeaderboardService
+	username string
+}
+
+func (u *userResolver) Username() string { return u.username }
+
+func (u *userResolver) Rating(ctx context.Context) (*int32, error) {
+	rating, err := loadersFrom(ctx).ratings.Load(ctx, u.username)()
+	if err != nil || rating == nil {
+		return nil, err
+	}
+	value := int32(*rating)
+	return &value, nil
+}
+
+func (u *userResolver) Rank(ctx context.Context) (*int32, error) {
+	entry, err := loadersFrom(ctx).entries.Load(ctx, u.username)()
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	value := int32(entry.Rank)
+	return &value, nil
+}
+
+func (u *userResolver) History(ctx context.Context, args struct{ Limit int32 }) ([]*scoreEventResolver, error) {
+	limit := int(args.Limit)
+	if limit < 1 || limit > maxHistoryLimit {
+		return nil, fmt.Errorf("history limit must be between 1 and %d", maxHistoryLimit)
+	}
+	if !auth.CanViewPrivate(ctx, u.username) {
+		return nil, errors.New("score history is only visible to the player")
+	}
+
+	events, err := loadersFrom(ctx).history.Load(ctx, historyKey{username: u.username, limit: limit})()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*scoreEventResolver, len(events))
+	for i := range events {
+		resolvers[i] = &scoreEventResolver{event: events[i]}
+	}
+	return resolvers, nil
+}
+
+func (u *userResolver) Neighbours(ctx context.Context, args struct{ Radius int32 }) ([]*entryResolver, error) {
+	radius := int(args.Radius)
+	if radius < 0 || radius > maxNeighbourRadius {
+		return nil, fmt.Errorf("radius must be between 0 and %d", maxNeighbourRadius)
+	}
+
+	entry, err := loadersFrom(ctx).entries.Load(ctx, u.username)()
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return []*entryResolver{}, nil
+	}
+
+	offset := entry.Rank - 1 - radius
+	if offset < 0 {
+		offset = 0
+	}
+	page, err := u.service.GetLeaderboardFor(ctx, auth.PlayerFromContext(ctx), offset, entry.Rank+radius-offset)
+	if err != nil {
+		return nil, err
+	}
+	return newEntryResolvers(ctx, u.service, page.Data), nil
+}
+
+// scoreEventResolver resolves ScoreEvent
+type scoreEventResolver struct {
+	event models.ScoreEvent
+}
+
+func (s *scoreEventResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(s.event.ID), 10))
+}
+func (s *scoreEventResolver) Username() string { return s.event.Username }
+func (s *scoreEventResolver) Rating() int32    { return int32(s.event.Rating) }
+func (s *scoreEventResolver) At() graphql.Time { return graphql.Time{Time: s.event.CreatedAt} }
+
+func (s *scoreEventResolver) PreviousRating() *int32 {
+	if s.event.PreviousRating == nil {
+		return nil
+	}
+	value := int32(*s.event.PreviousRating)
+	return &value
+}
+
+// leaderboardChangeResolver resolves LeaderboardChange
+type leaderboardChangeResolver struct {
+	eventType string
+	version   int64
+	since     *int64
+	changes   []*changeResolver
+}
+
+// toLeaderboardChange converts a hub event, or reports false for other hub messages
+func toLeaderboardChange(svc *service.LeaderboardService, event websocket.Event) (*leaderboardChangeResolver, bool) {
+	switch message := event.Message.(type) {
+	case websocket.VersionUpdate:
+		return &leaderboardChangeResolver{eventType: message.Type, version: message.Version}, true
+
+	case websocket.DeltaMessage:
+		changes := make([]*changeResolver, len(message.Changes))
+		for i, change := range message.Changes {
+			changes[i] = &changeResolver{service: svc, change: change}
+		}
+		since := message.Since
+		return &leaderboardChangeResolver{
+			eventType: message.Type,
+			version:   message.Version,
+			since:     &since,
+			changes:   changes,
+		}, true
+
+	default:
+		return nil, false
+	}
+}
+
+func (c *leaderboardChangeResolver) Type() string               { return c.eventType }
+func (c *leaderboardChangeResolver) Version() Int64             { return Int64(c.version) }
+func (c *leaderboardChangeResolver) Changes() []*changeResolver { return c.changes }
+
+func (c *leaderboardChangeResolver) Since() *Int64 {
+	if c.since == nil {
+		return nil
+	}
+	since := Int64(*c.since)
+	return &since
+}
+
+// changeResolver resolves Change
+type changeResolver struct {
+	service *service.LeaderboardService
+	change  models.ChangeEntry
+}
+
+func (c *changeResolver) Rank() int32    { return int32(c.change.Rank) }
+func (c *changeResolver) Rating() int32  { return int32(c.change.Rating) }
+func (c *changeResolver) Version() Int64 { return Int64(c.change.Version) }
+
+func (c *changeResolver) User() *userResolver {
+	return &userResolver{service: c.service, username: c.change.Username}
+}
+
+// rankChangeResolver resolves RankChange
+type rankChangeResolver struct {
+	service *service.LeaderboardService
+	message websocket.RankChanged
+}
+
+func (r *rankChangeResolver) OldRank() int32    { return int32(r.message.OldRank) }
+func (r *rankChangeResolver) NewRank() int32    { return int32(r.message.NewRank) }
+func (r *rankChangeResolver) Rating() int32     { return int32(r.message.Rating) }
+func (r *rankChangeResolver) TopN() int32       { return int32(r.message.TopN) }
+func (r *rankChangeResolver) Reasons() []string { return r.message.Reasons }
+func (r *rankChangeResolver) Version() Int64    { return Int64(r.message.Version) }
+
+func (r *rankChangeResolver) User() *userResolver {
+	return &userResolver{service: r.service, username: r.message.Username}
+}
@@ -0,0 +1,303 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/auth"
+
+	fiberws "github.com/gofiber/websocket/v2"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// Subprotocols lists the WebSocket subprotocols accepted at /graphql
+var Subprotocols = []string{"graphql-transport-ws"}
+
+const (
+	// The client must send connection_init within this time
+	connectionInitTimeout = 10 * time.Second
+
+	// Keepalive, matching the hub's WebSocket clients
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+
+	// Largest client message accepted (a subscribe carrying a query)
+	maxMessageSize = 64 * 1024
+
+	// Maximum number of concurrent operations per connection
+	maxOperationsPerConnection = 20
+)
+
+// graphql-transport-ws message types
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// graphql-transport-ws close codes
+const (
+	closeInvalidMessage     = 4400
+	closeUnauthorized       = 4401
+	closeInitTimeout        = 4408
+	closeSubscriberExists   = 4409
+	closeTooManyInitRequest = 4429
+)
+
+// message is a graphql-transport-ws message
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// connection is one graphql-transport-ws connection and its running operations
+type connection struct {
+	handler *Handler
+	conn    *fiberws.Conn
+
+	writeMu sync.Mutex
+
+	// Operation and keepalive goroutines, waited for before the handler returns since
+	// the connection is released once it does
+	running sync.WaitGroup
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+// ServeWS runs the graphql-transport-ws protocol on an upgraded connection
+// Queries may be sent as well as subscriptions; each runs until it completes, the
+// client sends complete or the connection closes
+func (h *Handler) ServeWS(conn *fiberws.Conn) {
+	c := &connection{
+		handler:    h,
+		conn:       conn,
+		operations: make(map[string]context.CancelFunc),
+	}
+
+	// Identity established by the upgrade request (player token or API key)
+	ctx, cancel := context.WithCancel(auth.FromLocals(context.Background(), conn.Locals))
+	defer func() {
+		cancel()
+		conn.Close()
+		c.running.Wait()
+	}()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(connectionInitTimeout))
+
+	c.running.Add(1)
+	go c.keepalive(ctx)
+
+	initialized := false
+	for {
+		var msg message
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if !initialized {
+				c.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			c.close(closeInvalidMessage, "Invalid message")
+			return
+		}
+
+		switch msg.Type {
+		case msgConnectionInit:
+			if initialized {
+				c.close(closeTooManyInitRequest, "Too many initialisation requests")
+				return
+			}
+			initialized = true
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(pongWait))
+			})
+			c.write(message{Type: msgConnectionAck})
+
+		case msgPing:
+			c.write(message{Type: msgPong})
+
+		case msgPong:
+			// Reply to a ping we never send; nothing to do
+
+		case msgSubscribe:
+			if !initialized {
+				c.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil || req.Query == "" {
+				c.close(closeInvalidMessage, "Invalid subscribe message")
+				return
+			}
+			if !c.start(ctx, msg.ID, req) {
+				return
+			}
+
+		case msgComplete:
+			c.stop(msg.ID)
+
+		default:
+			c.close(closeInvalidMessage, "Unknown message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// start runs an operation, returning false if the connection had to be closed
+func (c *connection) start(ctx context.Context, id string, req request) bool {
+	c.mu.Lock()
+	if _, exists := c.operations[id]; exists {
+		c.mu.Unlock()
+		c.close(closeSubscriberExists, "Subscriber for "+id+" already exists")
+		return false
+	}
+	if len(c.operations) >= maxOperationsPerConnection {
+		c.mu.Unlock()
+		c.writeErrors(id, "too many operations on this connection")
+		return true
+	}
+	opCtx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+	c.mu.Unlock()
+
+	// Subscription events are resolved with fresh values, so the loaders do not cache
+	opCtx = withLoaders(opCtx, newLoaders(c.handler.service, false))
+
+	responses, err := c.handler.schema.Subscribe(opCtx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.finish(id)
+		c.writeErrors(id, err.Error())
+		return true
+	}
+
+	c.running.Add(1)
+	go c.relay(id, responses)
+	return true
+}
+
+// relay sends an operation's results until it ends
+// A first result carrying only errors (e.g. validation) is sent as error, per protocol
+func (c *connection) relay(id string, responses <-chan interface{}) {
+	defer c.running.Done()
+
+	first := true
+	for response := range responses {
+		result, ok := response.(*graphql.Response)
+		if !ok {
+			continue
+		}
+		if first && result.Data == nil && len(result.Errors) > 0 {
+			if c.finish(id) {
+				payload, _ := json.Marshal(result.Errors)
+				c.write(message{ID: id, Type: msgError, Payload: payload})
+			}
+			return
+		}
+		first = false
+
+		payload, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("⚠️ Failed to encode GraphQL result: %v", err)
+			continue
+		}
+		if !c.write(message{ID: id, Type: msgNext, Payload: payload}) {
+			// Cancel and drain so the executor is not left blocked on the channel
+			c.finish(id)
+			for range responses {
+			}
+			return
+		}
+	}
+
+	// Only report completion if the client did not cancel the operation itself
+	if c.finish(id) {
+		c.write(message{ID: id, Type: msgComplete})
+	}
+}
+
+// stop cancels an operation at the client's request
+func (c *connection) stop(id string) {
+	c.mu.Lock()
+	cancel, ok := c.operations[id]
+	delete(c.operations, id)
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// finish forgets an operation that ended, reporting whether it was still running
+func (c *connection) finish(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cancel, ok := c.operations[id]
+	if ok {
+		cancel()
+		delete(c.operations, id)
+	}
+	return ok
+}
+
+// writeErrors sends an error message for an operation
+func (c *connection) writeErrors(id, errorMessage string) {
+	payload, _ := json.Marshal([]map[string]string{{"message": errorMessage}})
+	c.write(message{ID: id, Type: msgError, Payload: payload})
+}
+
+// write sends one message, returning false once the connection is broken
+func (c *connection) write(msg message) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.conn.Close()
+		return false
+	}
+	return true
+}
+
+// close ends the connection with a protocol close code
+func (c *connection) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.WriteControl(fiberws.CloseMessage, fiberws.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+}
+
+// keepalive pings the client until the connection ends, so dead connections are detected by the read deadline
+func (c *connection) keepalive(ctx context.Context) {
+	defer c.running.Done()
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.writeMu.Lock()
+			err := c.conn.WriteControl(fiberws.PingMessage, nil, time.Now().Add(writeWait))
+			c.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
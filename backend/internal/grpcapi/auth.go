@@ -0,0 +1,132 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"backend/internal/auth"
+	"backend/internal/grpcapi/leaderboardpb"
+	"backend/internal/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys carrying credentials, as the HTTP headers of the same name
+const (
+	apiKeyMetadata        = "x-api-key"
+	authorizationMetadata = "authorization"
+)
+
+// methodScopes is the scope each RPC requires, matching its HTTP equivalent
+// Read RPCs only require a key when reads do; Health is always open. A player token
+// is accepted instead of a key, as on POST /api/v1/scores.
+var methodScopes = map[string]string{
+	leaderboardpb.LeaderboardService_UpdateScore_FullMethodName:    models.ScopeWriteScores,
+	leaderboardpb.LeaderboardService_GetLeaderboard_FullMethodName: models.ScopeRead,
+	leaderboardpb.LeaderboardService_SearchUser_FullMethodName:     models.ScopeRead,
+	leaderboardpb.LeaderboardService_StreamChanges_FullMethodName:  models.ScopeRead,
+}
+
+// UnaryAuthInterceptor enforces API key scopes on unary RPCs
+func UnaryAuthInterceptor(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor enforces API key scopes on streaming RPCs
+func StreamAuthInterceptor(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(stream.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authorizedStream is a server stream whose context carries the authorized caller
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize checks the key in the call's metadata and attaches it to the context
+// Calls that need no key are still identified by whatever credentials they carry, as
+// HTTP requests are, so reads see what the caller is allowed to see.
+func authorize(ctx context.Context, authenticator *auth.Authenticator, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	scope, ok := methodScopes[method]
+	if !ok || (scope == models.ScopeRead && !authenticator.RequireReadKey()) {
+		return identify(ctx, authenticator, md)
+	}
+
+	if token := auth.BearerToken(firstValue(md, authorizationMetadata)); token != "" {
+		player, err := authenticator.VerifyPlayerToken(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		// Handlers check that the call concerns the player's own username
+		return auth.WithPlayer(ctx, player), nil
+	}
+
+	key, err := authenticator.Authenticate(ctx, firstValue(md, apiKeyMetadata))
+	switch {
+	case errors.Is(err, auth.ErrMissingAPIKey), errors.Is(err, auth.ErrInvalidAPIKey):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case err != nil:
+		log.Printf("❌ API key check failed: %v", err)
+		return nil, status.Error(codes.Unavailable, "API keys cannot be verified right now")
+	}
+
+	if !key.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %s scope", scope)
+	}
+	return auth.WithKey(ctx, key), nil
+}
+
+// identify attaches the player or key in the call's metadata, if any, to the context
+// Invalid credentials are still rejected.
+func identify(ctx context.Context, authenticator *auth.Authenticator, md metadata.MD) (context.Context, error) {
+	if token := auth.BearerToken(firstValue(md, authorizationMetadata)); token != "" {
+		player, err := authenticator.VerifyPlayerToken(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		ctx = auth.WithPlayer(ctx, player)
+	}
+
+	plaintext := firstValue(md, apiKeyMetadata)
+	if plaintext == "" {
+		return ctx, nil
+	}
+	key, err := authenticator.Authenticate(ctx, plaintext)
+	switch {
+	case errors.Is(err, auth.ErrInvalidAPIKey):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case err != nil:
+		log.Printf("❌ API key check failed: %v", err)
+		return nil, status.Error(codes.Unavailable, "API keys cannot be verified right now")
+	}
+	return auth.WithKey(ctx, key), nil
+}
+
+// firstValue returns the first value of a metadata key, or ""
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: leaderboard/v1/leaderboard.proto
+
+package leaderboardpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LeaderboardService_UpdateScore_FullMethodName    = "/kinetix.leaderboard.v1.LeaderboardService/UpdateScore"
+	LeaderboardService_GetLeaderboard_FullMethodName = "/kinetix.leaderboard.v1.LeaderboardService/GetLeaderboard"
+	LeaderboardService_SearchUser_FullMethodName     = "/kinetix.leaderboard.v1.LeaderboardService/SearchUser"
+	LeaderboardService_Health_FullMethodName         = "/kinetix.leaderboard.v1.LeaderboardService/Health"
+	LeaderboardService_StreamChanges_FullMethodName  = "/kinetix.leaderboard.v1.LeaderboardService/StreamChanges"
+)
+
+// LeaderboardServiceClient is the client API for LeaderboardService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// LeaderboardService mirrors the HTTP API for backend services
+type LeaderboardServiceClient interface {
+	// UpdateScore creates or updates a user's rating (POST /api/v1/scores)
+	UpdateScore(ctx context.Context, in *UpdateScoreRequest, opts ...grpc.CallOption) (*UpdateScoreResponse, error)
+	// GetLeaderboard returns a page of ranked entries (GET /api/v1/leaderboard)
+	GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*GetLeaderboardResponse, error)
+	// SearchUser returns a user's global rank and rating (GET /api/v1/search/:username)
+	SearchUser(ctx context.Context, in *SearchUserRequest, opts ...grpc.CallOption) (*SearchUserResponse, error)
+	// Health reports Redis/PostgreSQL status and circuit breakers (GET /api/v1/health)
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// StreamChanges streams the same version, delta and resync events as the WebSocket hub
+	StreamChanges(ctx context.Context, in *StreamChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error)
+}
+
+type leaderboardServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLeaderboardServiceClient(cc grpc.ClientConnInterface) LeaderboardServiceClient {
+	return &leaderboardServiceClient{cc}
+}
+
+func (c *leaderboardServiceClient) UpdateScore(ctx context.Context, in *UpdateScoreRequest, opts ...grpc.CallOption) (*UpdateScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateScoreResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_UpdateScore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*GetLeaderboardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLeaderboardResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_GetLeaderboard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) SearchUser(ctx context.Context, in *SearchUserRequest, opts ...grpc.CallOption) (*SearchUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchUserResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_SearchUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) StreamChanges(ctx context.Context, in *StreamChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LeaderboardService_ServiceDesc.Streams[0], LeaderboardService_StreamChanges_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamChangesRequest, ChangeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LeaderboardService_StreamChangesClient = grpc.ServerStreamingClient[ChangeEvent]
+
+// LeaderboardServiceServer is the server API for LeaderboardService service.
+// All implementations must embed UnimplementedLeaderboardServiceServer
+// for forward compatibility.
+//
+// LeaderboardService mirrors the HTTP API for backend services
+type LeaderboardServiceServer interface {
+	// UpdateScore creates or updates a user's rating (POST /api/v1/scores)
+	UpdateScore(context.Context, *UpdateScoreRequest) (*UpdateScoreResponse, error)
+	// GetLeaderboard returns a page of ranked entries (GET /api/v1/leaderboard)
+	GetLeaderboard(context.Context, *GetLeaderboardRequest) (*GetLeaderboardResponse, error)
+	// SearchUser returns a user's global rank and rating (GET /api/v1/search/:username)
+	SearchUser(context.Context, *SearchUserRequest) (*SearchUserResponse, error)
+	// Health reports Redis/PostgreSQL status and circuit breakers (GET /api/v1/health)
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// StreamChanges streams the same version, delta and resync events as the WebSocket hub
+	StreamChanges(*StreamChangesRequest, grpc.ServerStreamingServer[ChangeEvent]) error
+	mustEmbedUnimplementedLeaderboardServiceServer()
+}
+
+// UnimplementedLeaderboardServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLeaderboardServiceServer struct{}
+
+func (UnimplementedLeaderboardServiceServer) UpdateScore(context.Context, *UpdateScoreRequest) (*UpdateScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateScore not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) GetLeaderboard(context.Context, *GetLeaderboardRequest) (*GetLeaderboardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeaderboard not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) SearchUser(context.Context, *SearchUserRequest) (*SearchUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchUser not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) StreamChanges(*StreamChangesRequest, grpc.ServerStreamingServer[ChangeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamChanges not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) mustEmbedUnimplementedLeaderboardServiceServer() {}
+func (UnimplementedLeaderboardServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafeLeaderboardServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LeaderboardServiceServer will
+// result in compilation errors.
+type UnsafeLeaderboardServiceServer interface {
+	mustEmbedUnimplementedLeaderboardServiceServer()
+}
+
+func RegisterLeaderboardServiceServer(s grpc.ServiceRegistrar, srv LeaderboardServiceServer) {
+	// If the following call pancis, it indicates UnimplementedLeaderboardServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LeaderboardService_ServiceDesc, srv)
+}
+
+func _LeaderboardService_UpdateScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).UpdateScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_UpdateScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).UpdateScore(ctx, req.(*UpdateScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_GetLeaderboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeaderboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).GetLeaderboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_GetLeaderboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).GetLeaderboard(ctx, req.(*GetLeaderboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_SearchUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).SearchUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_SearchUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).SearchUser(ctx, req.(*SearchUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_StreamChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LeaderboardServiceServer).StreamChanges(m, &grpc.GenericServerStream[StreamChangesRequest, ChangeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LeaderboardService_StreamChangesServer = grpc.ServerStreamingServer[ChangeEvent]
+
+// LeaderboardService_ServiceDesc is the grpc.ServiceDesc for LeaderboardService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LeaderboardService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kinetix.leaderboard.v1.LeaderboardService",
+	HandlerType: (*LeaderboardServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UpdateScore",
+			Handler:    _LeaderboardService_UpdateScore_Handler,
+		},
+		{
+			MethodName: "GetLeaderboard",
+			Handler:    _LeaderboardService_GetLeaderboard_Handler,
+		},
+		{
+			MethodName: "SearchUser",
+			Handler:    _LeaderboardService_SearchUser_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _LeaderboardService_Health_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamChanges",
+			Handler:       _LeaderboardService_StreamChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "leaderboard/v1/leaderboard.proto",
+}
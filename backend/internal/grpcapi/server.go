@@ -0,0 +1,314 @@
+// Package grpcapi serves the leaderboard over gRPC next to the Fiber HTTP app
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=leaderboardpb --go_opt=module=backend/internal/grpcapi/leaderboardpb --go-grpc_out=leaderboardpb --go-grpc_opt=module=backend/internal/grpcapi/leaderboardpb leaderboard/v1/leaderboard.proto
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+
+	"backend/internal/auth"
+	"backend/internal/grpcapi/leaderboardpb"
+	"backend/internal/models"
+	"backend/internal/ratelimit"
+	"backend/internal/repository"
+	"backend/internal/service"
+	"backend/internal/websocket"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the gRPC LeaderboardService on top of the same service and hub
+// as the HTTP API
+type Server struct {
+	leaderboardpb.UnimplementedLeaderboardServiceServer
+
+	service   *service.LeaderboardService
+	hub       *websocket.Hub
+	validator *validator.Validate
+
+	signatures *auth.SignatureVerifier // nil when submissions need not be signed
+	limiter    *ratelimit.Limiter      // nil when calls are not rate limited
+}
+
+// NewServer creates a gRPC leaderboard server
+func NewServer(service *service.LeaderboardService, hub *websocket.Hub) *Server {
+	return &Server{
+		service:   service,
+		hub:       hub,
+		validator: validator.New(),
+	}
+}
+
+// WithRateLimiter limits UpdateScore calls by the rules of the scores route
+func (s *Server) WithRateLimiter(l *ratelimit.Limiter) *Server {
+	s.limiter = l
+	return s
+}
+
+// WithSignatures requires UpdateScore calls not made with a player token to be
+// HMAC-signed, checked by v
+// The signature travels in the x-signature, x-signature-nonce and x-signature-timestamp metadata
+func (s *Server) WithSignatures(v *auth.SignatureVerifier) *Server {
+	s.signatures = v
+	return s
+}
+
+// Register registers the server with a gRPC server
+func (s *Server) Register(grpcServer *grpc.Server) {
+	leaderboardpb.RegisterLeaderboardServiceServer(grpcServer, s)
+}
+
+// UpdateScore creates or updates a user's rating
+func (s *Server) UpdateScore(ctx context.Context, req *leaderboardpb.UpdateScoreRequest) (*leaderboardpb.UpdateScoreResponse, error) {
+	// Same validation rules as POST /api/v1/scores
+	scoreReq := models.ScoreRequest{Username: req.GetUsername(), Rating: int(req.GetRating())}
+	if err := s.validator.Struct(&scoreReq); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	// Players may only submit their own score
+	if player := auth.PlayerFromContext(ctx); player != "" && player != scoreReq.Username {
+		return nil, status.Error(codes.PermissionDenied, "token subject does not match username")
+	}
+
+	// Same limits as POST /api/v1/scores, sharing its buckets
+	if s.limiter != nil {
+		decision := s.limiter.Allow(ctx, ratelimit.RouteScores, map[string]string{
+			ratelimit.DimensionKey:      auth.ClientID(auth.KeyFromContext(ctx), auth.PlayerFromContext(ctx)),
+			ratelimit.DimensionIP:       peerIP(ctx),
+			ratelimit.DimensionUsername: scoreReq.Username,
+		})
+		if !decision.Allowed {
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(ratelimit.RetryAfterSeconds(decision.RetryAfter))))
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded per %s", decision.Dimension)
+		}
+	}
+
+	// Reject tampered or replayed submissions from game servers, as POST /api/v1/scores does
+	if s.signatures.Required(auth.PlayerFromContext(ctx)) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		sig := auth.Signature{
+			Value:     firstValue(md, auth.SignatureHeader),
+			Nonce:     firstValue(md, auth.SignatureNonceHeader),
+			Timestamp: firstValue(md, auth.SignatureTimestampHeader),
+		}
+		if err := s.signatures.Verify(ctx, scoreReq.Username, scoreReq.Rating, sig); err != nil {
+			if errors.Is(err, auth.ErrNonceStoreUnavailable) {
+				return nil, status.Error(codes.Unavailable, err.Error())
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "invalid signature: %v", err)
+		}
+	}
+
+	// Attributed to the key checked by UnaryAuthInterceptor
+	apiKeyID := auth.KeyID(auth.KeyFromContext(ctx))
+	review, err := s.service.SubmitScore(ctx, scoreReq.Username, scoreReq.Rating, apiKeyID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserBanned) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update score: %v", err)
+	}
+	if review != nil && review.Action == models.ReviewActionBlock {
+		return nil, status.Errorf(codes.FailedPrecondition, "score held for review (review %d)", review.ID)
+	}
+
+	return &leaderboardpb.UpdateScoreResponse{
+		Username: scoreReq.Username,
+		Rating:   int32(scoreReq.Rating),
+		Degraded: s.service.IsDegraded(),
+	}, nil
+}
+
+// GetLeaderboard returns a page of ranked entries
+func (s *Server) GetLeaderboard(ctx context.Context, req *leaderboardpb.GetLeaderboardRequest) (*leaderboardpb.GetLeaderboardResponse, error) {
+	offset := int(req.GetOffset())
+	if offset < 0 {
+		offset = 0
+	}
+
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100 // Max limit to prevent abuse
+	}
+
+	// Shadow-banned players still see themselves, as on GET /api/v1/leaderboard
+	leaderboard, err := s.service.GetLeaderboardFor(ctx, auth.PlayerFromContext(ctx), offset, limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve leaderboard: %v", err)
+	}
+
+	entries := make([]*leaderboardpb.Entry, 0, len(leaderboard.Data))
+	for _, entry := range leaderboard.Data {
+		entries = append(entries, toEntry(entry))
+	}
+
+	return &leaderboardpb.GetLeaderboardResponse{
+		Entries:  entries,
+		Offset:   int32(leaderboard.Offset),
+		Limit:    int32(leaderboard.Limit),
+		Total:    leaderboard.Total,
+		Degraded: s.service.IsDegraded(),
+	}, nil
+}
+
+// SearchUser returns a user's global rank and rating
+func (s *Server) SearchUser(ctx context.Context, req *leaderboardpb.SearchUserRequest) (*leaderboardpb.SearchUserResponse, error) {
+	if req.GetUsername() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
+
+	user, err := s.service.SearchUserFor(ctx, auth.PlayerFromContext(ctx), req.GetUsername())
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user %s not found", req.GetUsername())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to search user: %v", err)
+	}
+
+	return &leaderboardpb.SearchUserResponse{
+		GlobalRank: int32(user.GlobalRank),
+		Username:   user.Username,
+		Rating:     int32(user.Rating),
+		Degraded:   s.service.IsDegraded(),
+	}, nil
+}
+
+// Health reports whether the leaderboard is healthy, degraded or unavailable
+func (s *Server) Health(ctx context.Context, req *leaderboardpb.HealthRequest) (*leaderboardpb.HealthResponse, error) {
+	breakers := make(map[string]*leaderboardpb.BreakerState)
+	for name, snapshot := range s.service.BreakerStates() {
+		breakers[name] = &leaderboardpb.BreakerState{
+			State:               snapshot.State,
+			ConsecutiveFailures: int32(snapshot.ConsecutiveFailures),
+			TotalFailures:       snapshot.TotalFailures,
+			Rejected:            snapshot.Rejected,
+			LastError:           snapshot.LastError,
+		}
+	}
+
+	if err := s.service.HealthCheck(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "health check failed: %v", err)
+	}
+
+	if s.service.IsDegraded() {
+		return &leaderboardpb.HealthResponse{
+			Status:   "degraded",
+			Message:  "Redis unavailable, serving from PostgreSQL",
+			Breakers: breakers,
+		}, nil
+	}
+
+	return &leaderboardpb.HealthResponse{
+		Status:   "healthy",
+		Message:  "All systems operational",
+		Breakers: breakers,
+	}, nil
+}
+
+// StreamChanges streams version updates, deltas and resyncs from the hub until the
+// client cancels. A non-zero last_version replays missed changes first.
+func (s *Server) StreamChanges(req *leaderboardpb.StreamChangesRequest, stream leaderboardpb.LeaderboardService_StreamChangesServer) error {
+	if req.GetLastVersion() < 0 {
+		return status.Error(codes.InvalidArgument, "last_version must be non-negative")
+	}
+
+	changes := s.hub.OpenStream(websocket.TransportGRPC, websocket.ConnectOptions{
+		LastVersion: req.GetLastVersion(),
+	})
+	defer changes.Close()
+
+	ctx := stream.Context()
+	for {
+		event, err := changes.Next(ctx)
+		if err != nil {
+			switch {
+			case errors.Is(err, websocket.ErrSlowConsumer):
+				return status.Error(codes.ResourceExhausted, "stream fell too far behind; reconnect with last_version")
+			case errors.Is(err, websocket.ErrStreamClosed):
+				return status.Error(codes.Unavailable, "stream closed by server")
+			default:
+				return status.FromContextError(err).Err()
+			}
+		}
+
+		message := toChangeEvent(event)
+		if message == nil {
+			continue // Not a change event (e.g. presence)
+		}
+		if err := stream.Send(message); err != nil {
+			log.Printf("⚠️ gRPC change stream send failed: %v", err)
+			return err
+		}
+	}
+}
+
+// peerIP returns the IP address of the calling client, or "" if unknown
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// toEntry converts a leaderboard entry to its protobuf form
+func toEntry(entry models.LeaderboardEntry) *leaderboardpb.Entry {
+	return &leaderboardpb.Entry{
+		Rank:     int32(entry.Rank),
+		Username: entry.Username,
+		Rating:   int32(entry.Rating),
+	}
+}
+
+// toChangeEvent converts a hub event to a ChangeEvent, or nil for other hub messages
+func toChangeEvent(event websocket.Event) *leaderboardpb.ChangeEvent {
+	switch message := event.Message.(type) {
+	case websocket.VersionUpdate:
+		if message.Type == "RESYNC" {
+			return &leaderboardpb.ChangeEvent{Event: &leaderboardpb.ChangeEvent_Resync{
+				Resync: &leaderboardpb.Resync{Version: message.Version},
+			}}
+		}
+		return &leaderboardpb.ChangeEvent{Event: &leaderboardpb.ChangeEvent_VersionUpdate{
+			VersionUpdate: &leaderboardpb.VersionUpdate{Version: message.Version},
+		}}
+
+	case websocket.DeltaMessage:
+		changes := make([]*leaderboardpb.ChangeEntry, 0, len(message.Changes))
+		for _, change := range message.Changes {
+			changes = append(changes, &leaderboardpb.ChangeEntry{
+				Username: change.Username,
+				Rating:   int32(change.Rating),
+				Rank:     int32(change.Rank),
+				Version:  change.Version,
+			})
+		}
+		return &leaderboardpb.ChangeEvent{Event: &leaderboardpb.ChangeEvent_Delta{
+			Delta: &leaderboardpb.Delta{
+				Since:   message.Since,
+				Version: message.Version,
+				Changes: changes,
+			},
+		}}
+
+	default:
+		return nil
+	}
+}
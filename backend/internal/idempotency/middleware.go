@@ -0,0 +1,154 @@
+// Package idempotency makes retried requests safe: a request repeated with the same
+// Idempotency-Key gets the original response instead of being applied again
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// Header is the request header carrying the idempotency key
+	Header = "Idempotency-Key"
+
+	// ReplayedHeader is set on responses replayed for a duplicate request
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// Keys are 1-255 characters
+	maxKeyLength = 255
+
+	// A request holds its key for this long at most, so a crashed instance
+	// does not block retries until the key expires
+	pendingTTL = 30 * time.Second
+)
+
+// Store keeps idempotency keys and their responses in Redis
+type Store struct {
+	repo *repository.RedisRepository
+	ttl  time.Duration
+
+	stored     atomic.Int64 // Responses stored for replay
+	replayed   atomic.Int64 // Duplicates answered with a stored response
+	inProgress atomic.Int64 // Duplicates refused while the original was still running
+	mismatched atomic.Int64 // Keys reused with a different request
+	errors     atomic.Int64 // Requests handled without idempotency because Redis failed
+}
+
+// NewStore creates a store keeping responses for ttl
+func NewStore(repo *repository.RedisRepository, ttl time.Duration) *Store {
+	return &Store{repo: repo, ttl: ttl}
+}
+
+// Middleware honours the Idempotency-Key header of the requests it guards
+// Keys are scoped to the authenticated client, so it must run after the auth middleware
+// Only successful responses are stored; after an error the key is released for a retry
+// Requests without the header pass through unchanged
+func (s *Store) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(Header)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid idempotency key",
+				Message: "Idempotency-Key must be at most 255 characters",
+			})
+		}
+
+		// The same key may be used by different clients, and for different routes
+		recordKey := auth.ClientID(auth.KeyFromFiber(c), auth.PlayerFromFiber(c)) + ":" + c.Method() + ":" + c.Path() + ":" + key
+		fingerprint := fingerprint(c.Body())
+
+		existing, err := s.repo.ClaimIdempotencyKey(c.Context(), recordKey, fingerprint, pendingTTL)
+		if err != nil {
+			// Without Redis, a retry may apply twice; absolute score updates tolerate that
+			s.errors.Add(1)
+			log.Printf("⚠️ Idempotency key check failed, handling request without it: %v", err)
+			return c.Next()
+		}
+
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				s.mismatched.Add(1)
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(models.ErrorResponse{
+					Error:   "Idempotency key reused",
+					Message: "Idempotency-Key was already used with a different request",
+				})
+			case existing.Pending:
+				s.inProgress.Add(1)
+				return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+					Error:   "Request in progress",
+					Message: "a request with this Idempotency-Key is still being processed",
+				})
+			default:
+				s.replayed.Add(1)
+				c.Set(ReplayedHeader, "true")
+				c.Set(fiber.HeaderContentType, existing.ContentType)
+				return c.Status(existing.Status).Send(existing.Body)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			s.release(c, recordKey)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status < fiber.StatusOK || status >= fiber.StatusMultipleChoices {
+			s.release(c, recordKey)
+			return nil
+		}
+
+		record := repository.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		if err := s.repo.SaveIdempotentResponse(c.Context(), recordKey, record, s.ttl); err != nil {
+			s.errors.Add(1)
+			log.Printf("⚠️ Failed to store idempotent response: %v", err)
+			return nil
+		}
+		s.stored.Add(1)
+		return nil
+	}
+}
+
+// release forgets a key whose request did not succeed
+func (s *Store) release(c *fiber.Ctx, recordKey string) {
+	if err := s.repo.ReleaseIdempotencyKey(c.Context(), recordKey); err != nil {
+		log.Printf("⚠️ Failed to release idempotency key: %v", err)
+	}
+}
+
+// GetMetrics returns how duplicates were handled
+func (s *Store) GetMetrics() map[string]interface{} {
+	if s == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"stored":      s.stored.Load(),
+		"replayed":    s.replayed.Load(),
+		"in_progress": s.inProgress.Load(),
+		"mismatched":  s.mismatched.Load(),
+		"errors":      s.errors.Load(),
+	}
+}
+
+// fingerprint hashes a request body to detect a key reused for a different request
+func fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -205,7 +205,7 @@ func (sm *SimulationManager) simulationLoop(ctx context.Context) {
 
 				// Direct service call (bypasses HTTP stack)
 				sm.totalUpdates.Add(1)
-				if err := sm.service.UpdateScore(context.Background(), user.Username, newRating); err != nil {
+				if err := sm.service.UpdateScore(context.Background(), user.Username, newRating, 0); err != nil {
 					sm.errorCount.Add(1)
 					// Log only critical errors, not every failure
 					if sm.errorCount.Load()%100 == 1 {
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeRead        = "read"         // Read the leaderboard (only enforced when REQUIRE_READ_KEY is set)
+	ScopeWriteScores = "write-scores" // Submit scores
+	ScopeAdmin       = "admin"        // Manage keys and use admin endpoints; implies every other scope
+)
+
+// APIKey is a credential for the API, stored as a SHA-256 hash of the key
+// The plaintext key is only returned once, when the key is created
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"` // First characters of the key, to recognise it in listings
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     string     `gorm:"not null" json:"-"` // Comma-separated
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the key's scopes
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key grants scope; admin keys grant every scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.ScopeList() {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=3,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=read write-scores admin"`
+}
+
+// APIKeyResponse describes an API key; Key is only set when the key is created
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records one administrative or security-relevant action
+// The audit_log table is append-only: PostgreSQL rejects updates and deletes.
+type AuditEntry struct {
+	ID       uint            `gorm:"primarykey" json:"id"`
+	Actor    string          `gorm:"not null;index" json:"actor"` // "key:<id>", "player:<name>" or "system"
+	APIKeyID *uint           `json:"api_key_id,omitempty"`
+	Action   string          `gorm:"not null;index" json:"action"`
+	Target   string          `gorm:"index" json:"target,omitempty"` // What was acted on, e.g. "user:<name>" or "key:<id>"
+	Reason   string          `json:"reason,omitempty"`
+	Before   json.RawMessage `gorm:"type:jsonb;serializer:json" json:"before,omitempty" swaggertype:"object"`
+	After    json.RawMessage `gorm:"type:jsonb;serializer:json" json:"after,omitempty" swaggertype:"object"`
+	// CreatedAt is when the action happened
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// AuditListResponse is a page of audit entries
+type AuditListResponse struct {
+	Data   []AuditEntry `json:"data"`
+	Offset int          `json:"offset"`
+	Limit  int          `json:"limit"`
+	Total  int64        `json:"total"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Anti-cheat actions, from least to most severe
+const (
+	ReviewActionFlag  = "flag"  // The update was applied and queued for review
+	ReviewActionBlock = "block" // The update is held until a reviewer approves it
+)
+
+// Review states
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ScoreReview is a suspicious score update queued for review by the anti-cheat rules
+type ScoreReview struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	Username       string     `gorm:"not null;index" json:"username"`
+	PreviousRating *int       `json:"previous_rating"` // Rating when the update arrived (nil for new users)
+	Rating         int        `gorm:"not null" json:"rating"`
+	Action         string     `gorm:"not null" json:"action"`
+	Reasons        string     `gorm:"not null" json:"reasons"` // Violated rules, "; "-separated
+	APIKeyID       *uint      `json:"api_key_id,omitempty"`    // Key that submitted the update
+	Status         string     `gorm:"not null;default:pending;index" json:"status"`
+	ReviewedBy     *uint      `json:"reviewed_by,omitempty"` // Admin key that resolved the review
+	ReviewNote     string     `json:"review_note,omitempty"`
+	Reverted       bool       `gorm:"not null;default:false" json:"reverted"` // A rejected flagged update was undone
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (ScoreReview) TableName() string {
+	return "score_reviews"
+}
+
+// ReviewListResponse is a page of reviews
+type ReviewListResponse struct {
+	Data   []ScoreReview `json:"data"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+	Total  int64         `json:"total"`
+}
+
+// ResolveReviewRequest is the optional body of an approve or reject call
+type ResolveReviewRequest struct {
+	Note string `json:"note" validate:"max=500"`
+}
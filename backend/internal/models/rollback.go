@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// SetRatingRequest overrides a user's rating
+type SetRatingRequest struct {
+	Rating int    `json:"rating" validate:"required,min=100,max=5000"`
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// RollbackRequest selects score updates to revert, by user, by time window or both
+// Each matched user goes back to their rating before their first matched update.
+type RollbackRequest struct {
+	Usernames []string   `json:"usernames" validate:"max=1000,dive,min=3,max=50"`
+	From      *time.Time `json:"from"` // Inclusive
+	To        *time.Time `json:"to"`   // Exclusive (default: now)
+	Reason    string     `json:"reason" validate:"required,min=3,max=500"`
+	DryRun    bool       `json:"dry_run"` // Report the changes without applying them
+}
+
+// RollbackChange is what a rollback does to one user
+type RollbackChange struct {
+	Username string `json:"username"`
+	From     int    `json:"from"`
+	To       *int   `json:"to"` // nil when the user was created by a reverted update and is removed
+}
+
+// RollbackResponse lists the users a rollback changed (or would change, for dry runs)
+type RollbackResponse struct {
+	DryRun   bool             `json:"dry_run"`
+	Restored int              `json:"restored"`
+	Removed  int              `json:"removed"`
+	Changes  []RollbackChange `json:"changes"`
+}
@@ -4,13 +4,24 @@ import (
 	"time"
 )
 
+// Moderation states of a user
+const (
+	UserActive       = "active"        // Ranked normally
+	UserHidden       = "hidden"        // Off the board; submissions still recorded
+	UserBanned       = "banned"        // Off the board; submissions rejected
+	UserShadowBanned = "shadow-banned" // Off the board for everyone but the user themselves
+)
+
 // User represents a user in the leaderboard system
 type User struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	Username  string    `gorm:"uniqueIndex;not null" json:"username"`
-	Rating    int       `gorm:"not null;index" json:"rating"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `gorm:"primarykey" json:"id"`
+	Username        string     `gorm:"uniqueIndex;not null" json:"username"`
+	Rating          int        `gorm:"not null;index" json:"rating"`
+	Status          string     `gorm:"not null;default:active;index" json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // TableName specifies the table name for GORM
@@ -18,6 +29,37 @@ func (User) TableName() string {
 	return "users"
 }
 
+// ScoreEvent records one rating change of a user, written with every PostgreSQL upsert
+// PreviousRating is nil when the update created the user
+type ScoreEvent struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	Username       string    `gorm:"not null;index:idx_score_events_username_created,priority:1" json:"username"`
+	PreviousRating *int      `json:"previous_rating"`
+	Rating         int       `gorm:"not null" json:"rating"`
+	APIKeyID       *uint     `gorm:"index" json:"api_key_id,omitempty"` // Key that submitted the update (nil for internal writes)
+	Reason         string    `json:"reason,omitempty"`                  // Why an admin set the rating (overrides and rollbacks)
+	CreatedAt      time.Time `gorm:"index;index:idx_score_events_username_created,priority:2" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ScoreEvent) TableName() string {
+	return "score_events"
+}
+
+// ModerationRequest changes a user's moderation state
+type ModerationRequest struct {
+	Status string `json:"status" validate:"required,oneof=active hidden banned shadow-banned"`
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// ModeratedUsersResponse is a page of users that are not active
+type ModeratedUsersResponse struct {
+	Data   []User `json:"data"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Total  int64  `json:"total"`
+}
+
 // ScoreRequest represents the request payload for updating scores
 type ScoreRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
@@ -46,8 +88,48 @@ type SearchResponse struct {
 	Rating     int    `json:"rating"`
 }
 
+// ChangeEvent is published on every leaderboard write so hubs can push without polling
+// Bulk writes (e.g. a full sync) carry no username and mean "refetch everything"
+type ChangeEvent struct {
+	Version  int64  `json:"version"`
+	Username string `json:"username,omitempty"`
+	Rating   int    `json:"rating,omitempty"`
+	Bulk     bool   `json:"bulk,omitempty"`
+}
+
+// ChangeEntry is a user whose rating changed, with their current rank
+type ChangeEntry struct {
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Rank     int    `json:"rank"`
+	Version  int64  `json:"version"`
+}
+
+// ChangesResponse represents the response for the change feed
+// When ResyncRequired is set the client must refetch instead of patching
+type ChangesResponse struct {
+	Since          int64         `json:"since"`
+	Version        int64         `json:"version"`
+	Changes        []ChangeEntry `json:"changes"`
+	HasMore        bool          `json:"has_more"`
+	ResyncRequired bool          `json:"resync_required"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 }
+
+// ScoreHistoryResponse represents a user's recent rating changes, newest first
+type ScoreHistoryResponse struct {
+	Username string       `json:"username"`
+	History  []ScoreEvent `json:"history"`
+}
+
+// WaitResponse is returned by the long-poll endpoint
+type WaitResponse struct {
+	Version     int64                `json:"version"`
+	Changed     bool                 `json:"changed"`
+	Leaderboard *LeaderboardResponse `json:"leaderboard,omitempty"`
+}
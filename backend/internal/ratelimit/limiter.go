@@ -0,0 +1,193 @@
+// Package ratelimit throttles requests per client, per IP and per target username with
+// token buckets shared by all instances through Redis
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/repository"
+)
+
+// Dimensions a rule can limit by
+const (
+	DimensionKey      = "key"      // The authenticated client: API key, or player for player tokens
+	DimensionIP       = "ip"       // The client's IP address
+	DimensionUsername = "username" // The user a request targets, e.g. the username of a score update
+)
+
+// Routes with their own limits; a route may span several endpoints
+const (
+	RouteScores = "scores" // Score submissions over HTTP and gRPC
+	RouteReads  = "reads"  // Leaderboard reads, streams and GraphQL
+)
+
+// Rule allows Limit requests per Period for each distinct value of a dimension
+// Bursts of up to Limit requests are allowed after a quiet period
+type Rule struct {
+	Dimension string
+	Limit     int
+	Period    time.Duration
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s:%d/%s", r.Dimension, r.Limit, r.Period)
+}
+
+// ParseRules parses a comma-separated list of <dimension>:<limit>/<period> rules,
+// e.g. "key:20/1s,ip:50/1s,username:5/1m"; an empty spec means no limits
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		dimension, rate, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("rate limit rule %q: expected <dimension>:<limit>/<period>", part)
+		}
+		switch dimension {
+		case DimensionKey, DimensionIP, DimensionUsername:
+		default:
+			return nil, fmt.Errorf("rate limit rule %q: unknown dimension %q", part, dimension)
+		}
+
+		limitStr, periodStr, ok := strings.Cut(rate, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit rule %q: expected <dimension>:<limit>/<period>", part)
+		}
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("rate limit rule %q: limit must be a positive integer", part)
+		}
+		period, err := time.ParseDuration(periodStr)
+		if err != nil || period < time.Second {
+			return nil, fmt.Errorf("rate limit rule %q: period must be a duration of at least 1s", part)
+		}
+
+		rules = append(rules, Rule{Dimension: dimension, Limit: limit, Period: period})
+	}
+	return rules, nil
+}
+
+// Decision is the outcome of a rate limit check
+type Decision struct {
+	Allowed    bool
+	Dimension  string        // Dimension whose limit was exceeded
+	RetryAfter time.Duration // When the request may be retried
+}
+
+// routeLimits are the rules of one route and its decision counters
+type routeLimits struct {
+	rules   []Rule
+	allowed atomic.Int64
+	limited map[string]*atomic.Int64 // Dimension -> requests refused
+	errors  atomic.Int64             // Checks that failed and were let through
+}
+
+// Limiter checks requests against the rules configured for their route
+type Limiter struct {
+	repo   *repository.RedisRepository
+	routes map[string]*routeLimits
+}
+
+// NewLimiter creates a limiter without any limits; add them with WithRoute
+func NewLimiter(repo *repository.RedisRepository) *Limiter {
+	return &Limiter{
+		repo:   repo,
+		routes: make(map[string]*routeLimits),
+	}
+}
+
+// WithRoute limits the named route by rules; routes without rules are not limited
+func (l *Limiter) WithRoute(route string, rules []Rule) *Limiter {
+	limited := make(map[string]*atomic.Int64, len(rules))
+	for _, rule := range rules {
+		limited[rule.Dimension] = new(atomic.Int64)
+	}
+	l.routes[route] = &routeLimits{rules: rules, limited: limited}
+	return l
+}
+
+// Rules returns the rules of a route
+func (l *Limiter) Rules(route string) []Rule {
+	if limits, ok := l.routes[route]; ok {
+		return limits.rules
+	}
+	return nil
+}
+
+// Allow takes a token for a request to route from the bucket of each rule, keyed by the
+// request's value for the rule's dimension; rules whose value is empty are skipped
+// When Redis cannot be reached the request is allowed, so a Redis outage does not take
+// the API down with it (degraded mode keeps serving writes)
+func (l *Limiter) Allow(ctx context.Context, route string, values map[string]string) Decision {
+	limits, ok := l.routes[route]
+	if !ok || len(limits.rules) == 0 {
+		return Decision{Allowed: true}
+	}
+
+	buckets := make([]repository.TokenBucket, 0, len(limits.rules))
+	dimensions := make([]string, 0, len(limits.rules))
+	for _, rule := range limits.rules {
+		value := values[rule.Dimension]
+		if value == "" {
+			continue
+		}
+		buckets = append(buckets, repository.TokenBucket{
+			Key:      fmt.Sprintf("%s%s:%s:%s:%s", repository.RateLimitKeyPrefix, route, rule.Dimension, rule.Period, value),
+			Capacity: rule.Limit,
+			Period:   rule.Period,
+		})
+		dimensions = append(dimensions, rule.Dimension)
+	}
+	if len(buckets) == 0 {
+		limits.allowed.Add(1)
+		return Decision{Allowed: true}
+	}
+
+	denied, retryAfter, err := l.repo.TakeTokens(ctx, buckets)
+	if err != nil {
+		limits.errors.Add(1)
+		return Decision{Allowed: true}
+	}
+	if denied >= 0 {
+		limits.limited[dimensions[denied]].Add(1)
+		return Decision{Dimension: dimensions[denied], RetryAfter: retryAfter}
+	}
+
+	limits.allowed.Add(1)
+	return Decision{Allowed: true}
+}
+
+// GetMetrics returns each route's rules and decision counts
+func (l *Limiter) GetMetrics() map[string]interface{} {
+	if l == nil {
+		return map[string]interface{}{}
+	}
+
+	metrics := make(map[string]interface{}, len(l.routes))
+	for route, limits := range l.routes {
+		rules := make([]string, len(limits.rules))
+		for i, rule := range limits.rules {
+			rules[i] = rule.String()
+		}
+		limited := make(map[string]int64, len(limits.limited))
+		for dimension, count := range limits.limited {
+			limited[dimension] = count.Load()
+		}
+		metrics[route] = map[string]interface{}{
+			"rules":   rules,
+			"allowed": limits.allowed.Load(),
+			"limited": limited,
+			"errors":  limits.errors.Load(),
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware limits requests to the named route
+// It must run after the auth middleware, which identifies the client
+func (l *Limiter) Middleware(route string) fiber.Handler {
+	needsUsername := false
+	for _, rule := range l.Rules(route) {
+		needsUsername = needsUsername || rule.Dimension == DimensionUsername
+	}
+
+	return func(c *fiber.Ctx) error {
+		values := map[string]string{
+			DimensionKey: auth.ClientID(auth.KeyFromFiber(c), auth.PlayerFromFiber(c)), // Anonymous requests skip it
+			DimensionIP:  c.IP(), // From the proxy header only behind TRUSTED_PROXIES
+		}
+		if needsUsername {
+			values[DimensionUsername] = targetUsername(c)
+		}
+
+		decision := l.Allow(c.Context(), route, values)
+		if decision.Allowed {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(RetryAfterSeconds(decision.RetryAfter)))
+		return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+			Error:   "Too many requests",
+			Message: "rate limit exceeded per " + decision.Dimension,
+		})
+	}
+}
+
+// RetryAfterSeconds rounds a retry delay up to whole seconds for the Retry-After header
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// targetUsername returns the username a request targets: the :username route parameter,
+// or the username field of a JSON body such as a score update
+func targetUsername(c *fiber.Ctx) string {
+	if username := c.Params("username"); username != "" {
+		return username
+	}
+
+	var body struct {
+		Username string `json:"username"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return ""
+	}
+	return body.Username
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recentUpdatesKeyPrefix prefixes each user's sorted set of recently applied score
+// submissions, scored by submission time in ms, kept for the anti-cheat rules
+const recentUpdatesKeyPrefix = "leaderboard:anticheat:updates:"
+
+// RecentUpdate is one score submission of a user
+type RecentUpdate struct {
+	At       time.Time
+	Previous *int // Rating before the submission (nil for new users)
+	Rating   int
+}
+
+// RecentUpdates drops the user's submissions older than keep and returns the rest,
+// oldest first
+func (r *RedisRepository) RecentUpdates(ctx context.Context, username string, keep time.Duration) ([]RecentUpdate, error) {
+	key := recentUpdatesKeyPrefix + username
+	cutoff := time.Now().Add(-keep).UnixMilli()
+
+	var recent []redis.Z
+	err := r.breaker.Execute(func() error {
+		pipe := r.client.TxPipeline()
+		pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", cutoff))
+		rangeCmd := pipe.ZRangeWithScores(ctx, key, 0, -1)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		recent = rangeCmd.Val()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make([]RecentUpdate, 0, len(recent))
+	for _, z := range recent {
+		member, _ := z.Member.(string)
+		parts := strings.Split(member, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		rating, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		recentUpdate := RecentUpdate{At: time.Unix(0, nanos), Rating: rating}
+		if prev, err := strconv.Atoi(parts[1]); err == nil {
+			recentUpdate.Previous = &prev
+		}
+		updates = append(updates, recentUpdate)
+	}
+	return updates, nil
+}
+
+// RecordUpdate adds an applied submission to the user's recent updates, kept for keep
+func (r *RedisRepository) RecordUpdate(ctx context.Context, username string, update RecentUpdate, keep time.Duration) error {
+	key := recentUpdatesKeyPrefix + username
+
+	previous := "-"
+	if update.Previous != nil {
+		previous = strconv.Itoa(*update.Previous)
+	}
+
+	return r.breaker.Execute(func() error {
+		pipe := r.client.TxPipeline()
+		pipe.ZAdd(ctx, key, redis.Z{
+			Score:  float64(update.At.UnixMilli()),
+			Member: fmt.Sprintf("%d:%s:%d", update.At.UnixNano(), previous, update.Rating),
+		})
+		pipe.PExpire(ctx, key, keep)
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateAPIKey stores a new API key
+func (r *PostgresRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Create(key).Error
+	})
+}
+
+// GetAPIKeyByHash retrieves an active (not revoked) API key by the hash of its key
+// Returns ErrAPIKeyNotFound if no active key matches
+func (r *PostgresRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.breaker.Execute(func() error {
+		err := r.db.WithContext(ctx).
+			Where("key_hash = ? AND revoked_at IS NULL", keyHash).
+			First(&key).Error
+		if err == gorm.ErrRecordNotFound {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retrieves every API key, including revoked ones, newest first
+func (r *PostgresRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Order("id DESC").Find(&keys).Error
+	})
+	return keys, err
+}
+
+// RevokeAPIKey marks a key as revoked and returns it
+// Returns ErrAPIKeyNotFound if the key does not exist or is already revoked
+func (r *PostgresRepository) RevokeAPIKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.breaker.Execute(func() error {
+		now := time.Now()
+		tx := r.db.WithContext(ctx).Model(&models.APIKey{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now)
+		if tx.Error != nil {
+			return tx.Error
+		}
+		if tx.RowsAffected == 0 {
+			return ErrAPIKeyNotFound
+		}
+		return r.db.WithContext(ctx).First(&key, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// TouchAPIKey records that a key was used
+func (r *PostgresRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Model(&models.APIKey{}).
+			Where("id = ?", id).
+			Update("last_used_at", usedAt).Error
+	})
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// auditBatchSize bounds the entries inserted per statement
+const auditBatchSize = 500
+
+// AuditFilter narrows an audit log listing; zero fields match everything
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	From   *time.Time // Inclusive
+	To     *time.Time // Exclusive
+}
+
+// protectAuditLog makes audit_log append-only with a trigger rejecting updates and deletes
+func (r *PostgresRepository) protectAuditLog() error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_log is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log`,
+		`CREATE TRIGGER audit_log_append_only
+			BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+			FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()`,
+	}
+	for _, statement := range statements {
+		if err := r.db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateAuditEntries appends entries to the audit log
+func (r *PostgresRepository) CreateAuditEntries(ctx context.Context, entries []models.AuditEntry) error {
+	return r.breaker.Execute(func() error {
+		return createAuditEntries(r.db.WithContext(ctx), entries)
+	})
+}
+
+// createAuditEntries appends entries to the audit log within db, so writes that record
+// their own entries commit or roll back together with them
+func createAuditEntries(db *gorm.DB, entries []models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(&entries, auditBatchSize).Error
+}
+
+// ListAuditEntries retrieves a page of the audit log, newest first, and the total count
+func (r *PostgresRepository) ListAuditEntries(ctx context.Context, filter AuditFilter, offset, limit int) ([]models.AuditEntry, int64, error) {
+	var entries []models.AuditEntry
+	var total int64
+	err := r.breaker.Execute(func() error {
+		query := r.db.WithContext(ctx).Model(&models.AuditEntry{})
+		if filter.Actor != "" {
+			query = query.Where("actor = ?", filter.Actor)
+		}
+		if filter.Action != "" {
+			query = query.Where("action = ?", filter.Action)
+		}
+		if filter.Target != "" {
+			query = query.Where("target = ?", filter.Target)
+		}
+		if filter.From != nil {
+			query = query.Where("created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			query = query.Where("created_at < ?", *filter.To)
+		}
+		if err := query.Count(&total).Error; err != nil {
+			return err
+		}
+		return query.Order("id DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	})
+	return entries, total, err
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ChangelogKey is the Redis stream recording every write, with entry IDs "<version>-0"
+	ChangelogKey = "leaderboard:changelog"
+
+	// ChangelogMaxLen caps the changelog (approximate trimming)
+	ChangelogMaxLen = 10_000
+)
+
+// recordChangeLua appends to the changelog under the next version, bumps the version and
+// publishes a ChangeEvent. The changelog entry is written first: if XADD fails the script
+// errors out before the version moves, so every version has its entry. Writes the
+// embedding script made before it are not undone; the caller gets the error.
+// Scripts embedding it must define versionKey and changelogKey.
+// ARGV: maxlen, channel, username, rating, bulk ("1" or "0")
+const recordChangeLua = `
+local version = tonumber(redis.call('GET', versionKey) or '0') + 1
+local event = {version = version}
+if ARGV[5] == '1' then
+	event.bulk = true
+	redis.call('XADD', changelogKey, 'MAXLEN', '~', ARGV[1], version .. '-0', 'bulk', '1')
+else
+	event.username = ARGV[3]
+	event.rating = tonumber(ARGV[4])
+	redis.call('XADD', changelogKey, 'MAXLEN', '~', ARGV[1], version .. '-0', 'username', ARGV[3], 'rating', ARGV[4])
+end
+redis.call('SET', versionKey, version)
+redis.call('PUBLISH', ARGV[2], cjson.encode(event))
+return version
+`
+
+// updateScoreScript atomically writes a score, bumps the version, appends to the
+// changelog and publishes the change
+// Moderated users only get their rating stored: they stay off the board, and their
+// writes are not published
+// KEYS: ratings, metadata, version, changelog, moderation   ARGV: see recordChangeLua, plus composite score
+var updateScoreScript = redis.NewScript(`
+redis.call('HSET', KEYS[2], ARGV[3], ARGV[4])
+if redis.call('HEXISTS', KEYS[5], ARGV[3]) == 1 then
+	return tonumber(redis.call('GET', KEYS[3]) or '0')
+end
+redis.call('ZADD', KEYS[1], ARGV[6], ARGV[3])
+local versionKey, changelogKey = KEYS[3], KEYS[4]
+` + recordChangeLua)
+
+// recordChangeScript bumps the version for a bulk write
+// KEYS: version, changelog   ARGV: see recordChangeLua
+var recordChangeScript = redis.NewScript(`
+local versionKey, changelogKey = KEYS[1], KEYS[2]
+` + recordChangeLua)
+
+// GetChangesSince returns changelog entries with a version greater than since, oldest first
+// complete is false when entries after since have already been trimmed from the changelog
+// or any version up to the current one has no entry, in which case the caller cannot
+// patch its state and must refetch everything
+func (r *RedisRepository) GetChangesSince(ctx context.Context, since int64, limit int64) (changes []models.ChangeEvent, complete bool, err error) {
+	var (
+		currentCmd *redis.StringCmd
+		oldestCmd  *redis.XMessageSliceCmd
+		rangeCmd   *redis.XMessageSliceCmd
+	)
+
+	err = r.breaker.Execute(func() error {
+		pipe := r.client.Pipeline()
+		currentCmd = pipe.Get(ctx, VersionKey)
+		oldestCmd = pipe.XRangeN(ctx, ChangelogKey, "-", "+", 1)
+		rangeCmd = pipe.XRangeN(ctx, ChangelogKey, fmt.Sprintf("%d-0", since+1), "+", limit)
+		_, err := pipe.Exec(ctx)
+		if err == redis.Nil {
+			return nil // Version not set yet
+		}
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	current, _ := currentCmd.Int64()
+	if since >= current {
+		return []models.ChangeEvent{}, true, nil
+	}
+
+	// The first missing version must still be retained
+	oldest := oldestCmd.Val()
+	if len(oldest) == 0 || parseStreamVersion(oldest[0].ID) > since+1 {
+		return nil, false, nil
+	}
+
+	// Versions must follow on from since without gaps, up to the current version unless
+	// the page was cut at limit
+	messages := rangeCmd.Val()
+	changes = make([]models.ChangeEvent, 0, len(messages))
+	expected := since + 1
+	for _, msg := range messages {
+		event := parseChangelogEntry(msg)
+		if event.Version != expected {
+			return nil, false, nil
+		}
+		changes = append(changes, event)
+		expected++
+	}
+	if int64(len(messages)) < limit && expected <= current {
+		return nil, false, nil
+	}
+	return changes, true, nil
+}
+
+// parseChangelogEntry converts a changelog stream entry to a ChangeEvent
+func parseChangelogEntry(msg redis.XMessage) models.ChangeEvent {
+	event := models.ChangeEvent{Version: parseStreamVersion(msg.ID)}
+	if bulk, _ := msg.Values["bulk"].(string); bulk == "1" {
+		event.Bulk = true
+		return event
+	}
+	event.Username, _ = msg.Values["username"].(string)
+	if rating, ok := msg.Values["rating"].(string); ok {
+		event.Rating, _ = strconv.Atoi(rating)
+	}
+	return event
+}
+
+// parseStreamVersion extracts the version from a "<version>-0" stream ID
+func parseStreamVersion(id string) int64 {
+	ms, _, _ := strings.Cut(id, "-")
+	version, _ := strconv.ParseInt(ms, 10, 64)
+	return version
+}
+
+// GetUserPositions returns each user's 1-indexed position in the sorted set using a single pipeline
+// Users missing from the leaderboard are omitted
+func (r *RedisRepository) GetUserPositions(ctx context.Context, usernames []string) (map[string]int, error) {
+	positions := make(map[string]int, len(usernames))
+	if len(usernames) == 0 {
+		return positions, nil
+	}
+
+	cmds := make([]*redis.IntCmd, len(usernames))
+	err := r.breaker.Execute(func() error {
+		pipe := r.client.Pipeline()
+		for i, username := range usernames {
+			cmds[i] = pipe.ZRevRank(ctx, LeaderboardKey, username)
+		}
+		_, err := pipe.Exec(ctx)
+		if err == redis.Nil {
+			return nil // Some users are missing, handled below
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		if position, err := cmd.Result(); err == nil {
+			positions[usernames[i]] = int(position) + 1
+		}
+	}
+	return positions, nil
+}
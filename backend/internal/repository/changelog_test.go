@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*RedisRepository, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisRepository(client), server
+}
+
+func TestGetChangesSince(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRedis(t)
+
+	for i, username := range []string{"alice", "bob", "carol"} {
+		if err := repo.UpdateScore(ctx, username, 1000+i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, complete, err := repo.GetChangesSince(ctx, 1, 100)
+	if err != nil || !complete {
+		t.Fatalf("complete %v, err %v", complete, err)
+	}
+	if len(changes) != 2 || changes[0].Username != "bob" || changes[1].Version != 3 {
+		t.Fatalf("changes = %+v, want bob at 2 and carol at 3", changes)
+	}
+
+	changes, complete, err = repo.GetChangesSince(ctx, 0, 2)
+	if err != nil || !complete || len(changes) != 2 {
+		t.Fatalf("limited page: %d changes, complete %v, err %v", len(changes), complete, err)
+	}
+
+	// A version without a changelog entry can't be patched over
+	if err := repo.client.XDel(ctx, ChangelogKey, "2-0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, complete, err := repo.GetChangesSince(ctx, 1, 100); err != nil || complete {
+		t.Fatalf("gap in the middle: complete %v, err %v, want incomplete", complete, err)
+	}
+
+	// Nor can a missing latest entry
+	if err := repo.client.XDel(ctx, ChangelogKey, "3-0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, complete, err := repo.GetChangesSince(ctx, 0, 100); err != nil || complete {
+		t.Fatalf("gap at the end: complete %v, err %v, want incomplete", complete, err)
+	}
+}
+
+func TestRecordChangeFailsWithChangelog(t *testing.T) {
+	ctx := context.Background()
+	repo, server := newTestRedis(t)
+
+	if err := repo.UpdateScore(ctx, "alice", 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// XADD fails against a key of the wrong type, which must fail the write and leave the version alone
+	server.Del(ChangelogKey)
+	if err := server.Set(ChangelogKey, "not a stream"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateScore(ctx, "bob", 1100); err == nil {
+		t.Fatal("write succeeded without a changelog entry")
+	}
+
+	version, err := repo.GetLeaderboardVersion(ctx)
+	if err != nil || version != 1 {
+		t.Fatalf("version = %d, err %v, want 1", version, err)
+	}
+}
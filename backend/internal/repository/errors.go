@@ -0,0 +1,12 @@
+package repository
+
+import "errors"
+
+// ErrUserNotFound is returned when a user does not exist in Redis or PostgreSQL
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrAPIKeyNotFound is returned when an API key does not exist
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// ErrReviewNotFound is returned when a review does not exist or is no longer pending
+var ErrReviewNotFound = errors.New("review not found")
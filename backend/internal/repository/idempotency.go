@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyKeyPrefix prefixes the records of idempotency keys
+const IdempotencyKeyPrefix = "leaderboard:idempotency:"
+
+// IdempotencyRecord is what an idempotency key stands for: a request still being handled
+// (Pending) or the response to replay for its duplicates
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"` // Hash of the request the key was first used with
+	Pending     bool   `json:"pending,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// claimIdempotencyScript stores a pending record unless the key already has one
+// KEYS: record   ARGV: pending record, TTL in ms
+// Returns nil when claimed, else the existing record
+var claimIdempotencyScript = redis.NewScript(`
+local existing = redis.call('GET', KEYS[1])
+if existing then
+  return existing
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+
+// ClaimIdempotencyKey marks key as pending for ttl, unless it is already in use
+// It returns nil when the key was claimed, or the record the key already holds
+func (r *RedisRepository) ClaimIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	pending, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint, Pending: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var existing string
+	err = r.breaker.Execute(func() error {
+		var err error
+		existing, err = claimIdempotencyScript.Run(ctx, r.client, []string{IdempotencyKeyPrefix + key}, pending, ttl.Milliseconds()).Text()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	if err != nil || existing == "" {
+		return nil, err
+	}
+
+	var record IdempotencyRecord
+	if err := json.Unmarshal([]byte(existing), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SaveIdempotentResponse replaces the pending record of key with the response to replay
+func (r *RedisRepository) SaveIdempotentResponse(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return r.breaker.Execute(func() error {
+		return r.client.Set(ctx, IdempotencyKeyPrefix+key, data, ttl).Err()
+	})
+}
+
+// ReleaseIdempotencyKey forgets key, so the request can be retried with it
+func (r *RedisRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return r.breaker.Execute(func() error {
+		return r.client.Del(ctx, IdempotencyKeyPrefix+key).Err()
+	})
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ModerationKey is the Redis hash of users that are not active, username -> status
+// Score writes leave these users out of the ranking set
+const ModerationKey = "leaderboard:moderation"
+
+// moderateScript changes a user's moderation state in Redis
+// Moderated users leave the ranking set, recorded as a bulk change so clients refetch;
+// users made active again rejoin it with their current rating, recorded as a normal change
+// KEYS: ratings, metadata, moderation, version, changelog
+// ARGV: see recordChangeLua, plus status and composite score
+var moderateScript = redis.NewScript(`
+local versionKey, changelogKey = KEYS[4], KEYS[5]
+redis.call('HSET', KEYS[2], ARGV[3], ARGV[4])
+if ARGV[6] == '` + models.UserActive + `' then
+	redis.call('HDEL', KEYS[3], ARGV[3])
+	if redis.call('ZADD', KEYS[1], 'NX', ARGV[7], ARGV[3]) == 0 then
+		return 0
+	end
+else
+	redis.call('HSET', KEYS[3], ARGV[3], ARGV[6])
+	if redis.call('ZREM', KEYS[1], ARGV[3]) == 0 then
+		return 0
+	end
+end
+` + recordChangeLua)
+
+// pruneModeratedScript removes every moderated user from the ranking set
+// KEYS: ratings, moderation
+var pruneModeratedScript = redis.NewScript(`
+local users = redis.call('HKEYS', KEYS[2])
+for _, username in ipairs(users) do
+	redis.call('ZREM', KEYS[1], username)
+end
+return #users
+`)
+
+// SetModeration applies a user's moderation status to the ranking set
+// rating is the user's current rating, used when they rejoin the board
+func (r *RedisRepository) SetModeration(ctx context.Context, username, status string, rating int) error {
+	compositeScore := ComputeCompositeScore(rating, time.Now().UnixNano())
+
+	// Removals are published as bulk changes, since change events cannot express them
+	bulk := "1"
+	if status == models.UserActive {
+		bulk = "0"
+	}
+
+	return r.breaker.Execute(func() error {
+		return moderateScript.Run(ctx, r.client,
+			[]string{LeaderboardKey, MetadataKey, ModerationKey, VersionKey, ChangelogKey},
+			ChangelogMaxLen, ChangesChannel, username, rating, bulk,
+			status, strconv.FormatFloat(compositeScore, 'f', -1, 64),
+		).Err()
+	})
+}
+
+// ReplaceModeration replaces every moderation status in Redis, e.g. during a full sync
+// Users in statuses are removed from the ranking set
+func (r *RedisRepository) ReplaceModeration(ctx context.Context, statuses map[string]string) error {
+	return r.breaker.Execute(func() error {
+		pipe := r.client.TxPipeline()
+		pipe.Del(ctx, ModerationKey)
+		if len(statuses) > 0 {
+			values := make(map[string]interface{}, len(statuses))
+			for username, status := range statuses {
+				values[username] = status
+			}
+			pipe.HSet(ctx, ModerationKey, values)
+			pruneModeratedScript.Eval(ctx, pipe, []string{LeaderboardKey, ModerationKey})
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+}
+
+// GetModerationStatus returns a user's moderation status (active unless moderated)
+func (r *RedisRepository) GetModerationStatus(ctx context.Context, username string) (string, error) {
+	var status string
+	err := r.breaker.Execute(func() error {
+		var err error
+		status, err = r.client.HGet(ctx, ModerationKey, username).Result()
+		if err == redis.Nil {
+			status = models.UserActive
+			return nil
+		}
+		return err
+	})
+	return status, err
+}
+
+// CountAbove returns how many ranked users have a rating above rating
+func (r *RedisRepository) CountAbove(ctx context.Context, rating int) (int64, error) {
+	var count int64
+	err := r.breaker.Execute(func() error {
+		var err error
+		// Composite scores of rating r lie in (r, r+1), so anything from rating+1 is higher
+		count, err = r.client.ZCount(ctx, LeaderboardKey, strconv.Itoa(rating+1), "+inf").Result()
+		return err
+	})
+	return count, err
+}
+
+// SetUserStatus changes a user's moderation status and returns the user
+// audited builds the audit entries of the change, written in the same transaction.
+// Returns ErrUserNotFound if the user does not exist
+func (r *PostgresRepository) SetUserStatus(ctx context.Context, username, status, reason string, audited func(before, after *models.User) []models.AuditEntry) (*models.User, error) {
+	var user models.User
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var previous models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("username = ?", username).
+				First(&previous).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrUserNotFound
+				}
+				return err
+			}
+
+			if err := tx.Model(&models.User{}).
+				Where("username = ?", username).
+				Updates(map[string]interface{}{
+					"status":            status,
+					"status_reason":     reason,
+					"status_changed_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+				return err
+			}
+
+			if audited == nil {
+				return nil
+			}
+			return createAuditEntries(tx, audited(&previous, &user))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListModeratedUsers retrieves a page of users that are not active, most recently
+// moderated first, and their total count; status narrows the list to one state
+func (r *PostgresRepository) ListModeratedUsers(ctx context.Context, status string, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+	err := r.breaker.Execute(func() error {
+		query := r.db.WithContext(ctx).Model(&models.User{})
+		if status != "" {
+			query = query.Where("status = ?", status)
+		} else {
+			query = query.Where("status <> ?", models.UserActive)
+		}
+		if err := query.Count(&total).Error; err != nil {
+			return err
+		}
+		return query.Order("status_changed_at DESC").Offset(offset).Limit(limit).Find(&users).Error
+	})
+	return users, total, err
+}
+
+// GetModeratedUsers returns the status of every user that is not active
+func (r *PostgresRepository) GetModeratedUsers(ctx context.Context) (map[string]string, error) {
+	var users []models.User
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Select("username", "status").
+			Where("status <> ?", models.UserActive).
+			Find(&users).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(users))
+	for _, user := range users {
+		statuses[user.Username] = user.Status
+	}
+	return statuses, nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// nonceKeyPrefix prefixes the keys recording nonces of signed score submissions
+const nonceKeyPrefix = "leaderboard:nonce:"
+
+// ClaimNonce records a submission nonce for ttl and reports whether it was unused
+// SET NX makes the claim atomic across instances, so a replayed nonce is seen by all of them
+func (r *RedisRepository) ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	var claimed bool
+	err := r.breaker.Execute(func() error {
+		var err error
+		claimed, err = r.client.SetNX(ctx, nonceKeyPrefix+nonce, 1, ttl).Result()
+		return err
+	})
+	return claimed, err
+}
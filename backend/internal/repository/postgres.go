@@ -2,8 +2,9 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
+	"backend/internal/breaker"
 	"backend/internal/models"
 
 	"gorm.io/gorm"
@@ -11,39 +12,92 @@ import (
 )
 
 // PostgresRepository handles all PostgreSQL operations
+// Every operation runs through a circuit breaker so a sick database fails fast
 type PostgresRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	breaker *breaker.Breaker
 }
 
-// NewPostgresRepository creates a new Postgres repository
+// NewPostgresRepository creates a new Postgres repository with a default circuit breaker
 func NewPostgresRepository(db *gorm.DB) *PostgresRepository {
 	return &PostgresRepository{
-		db: db,
+		db:      db,
+		breaker: NewPostgresBreaker(breaker.DefaultConfig()),
 	}
 }
 
+// NewPostgresBreaker creates a circuit breaker that ignores missing rows and cancelled requests
+func NewPostgresBreaker(cfg breaker.Config) *breaker.Breaker {
+	return breaker.New("postgres", cfg, func(err error) bool {
+		return !errors.Is(err, gorm.ErrRecordNotFound) &&
+			!errors.Is(err, ErrUserNotFound) &&
+			!errors.Is(err, ErrAPIKeyNotFound) &&
+			!errors.Is(err, ErrReviewNotFound) &&
+			!errors.Is(err, context.Canceled)
+	})
+}
+
+// WithBreaker replaces the repository's circuit breaker
+func (r *PostgresRepository) WithBreaker(cb *breaker.Breaker) *PostgresRepository {
+	r.breaker = cb
+	return r
+}
+
+// Breaker returns the circuit breaker guarding PostgreSQL operations
+func (r *PostgresRepository) Breaker() *breaker.Breaker {
+	return r.breaker
+}
+
 // UpsertUser creates or updates a user in PostgreSQL
-// Uses ON CONFLICT to handle upserts efficiently
-func (r *PostgresRepository) UpsertUser(ctx context.Context, username string, rating int) error {
+// Uses ON CONFLICT to handle upserts efficiently. The change is recorded in
+// score_events in the same transaction, so history never disagrees with users.
+// apiKeyID attributes the change to the API key that submitted it (0 for internal writes).
+func (r *PostgresRepository) UpsertUser(ctx context.Context, username string, rating int, apiKeyID uint) error {
 	user := models.User{
 		Username: username,
 		Rating:   rating,
 	}
 
-	// Use GORM's Clauses for UPSERT (INSERT ... ON CONFLICT ... DO UPDATE)
-	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "username"}},
-		DoUpdates: clause.AssignmentColumns([]string{"rating", "updated_at"}),
-	}).Create(&user).Error
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Lock the current row so concurrent upserts record consecutive history
+			var previous []int
+			if err := tx.Model(&models.User{}).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("username = ?", username).
+				Pluck("rating", &previous).Error; err != nil {
+				return err
+			}
+
+			// Use GORM's Clauses for UPSERT (INSERT ... ON CONFLICT ... DO UPDATE)
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "username"}},
+				DoUpdates: clause.AssignmentColumns([]string{"rating", "updated_at"}),
+			}).Create(&user).Error; err != nil {
+				return err
+			}
+
+			event := models.ScoreEvent{Username: username, Rating: rating}
+			if len(previous) > 0 {
+				event.PreviousRating = &previous[0]
+			}
+			if apiKeyID != 0 {
+				event.APIKeyID = &apiKeyID
+			}
+			return tx.Create(&event).Error
+		})
+	})
 }
 
 // GetUser retrieves a user by username
 func (r *PostgresRepository) GetUser(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	})
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -53,25 +107,105 @@ func (r *PostgresRepository) GetUser(ctx context.Context, username string) (*mod
 // GetAllUsers retrieves all users (used for seeding Redis)
 func (r *PostgresRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	err := r.db.WithContext(ctx).Order("rating DESC").Find(&users).Error
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Order("rating DESC").Find(&users).Error
+	})
 	return users, err
 }
 
+// GetLeaderboardPage retrieves a page of the leaderboard directly from PostgreSQL
+// Ranks are computed with RANK() OVER (ORDER BY rating DESC), which matches the
+// 1224 tie-aware ranking used for Redis reads. Used as the degraded read path.
+// Only active users are ranked; moderated users are kept off the board.
+func (r *PostgresRepository) GetLeaderboardPage(ctx context.Context, offset, limit int) ([]models.LeaderboardEntry, error) {
+	entries := make([]models.LeaderboardEntry, 0, limit)
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Raw(`
+			SELECT RANK() OVER (ORDER BY rating DESC) AS rank, username, rating
+			FROM users
+			WHERE status = ?
+			ORDER BY rating DESC, updated_at ASC, id ASC
+			OFFSET ? LIMIT ?`, models.UserActive, offset, limit).Scan(&entries).Error
+	})
+	return entries, err
+}
+
+// GetUserRank retrieves a user's rank, rating and moderation status directly from PostgreSQL
+// The rank counts active users with a higher rating, so a moderated user gets the rank
+// they would have on the board
+// Returns ErrUserNotFound if the user does not exist
+func (r *PostgresRepository) GetUserRank(ctx context.Context, username string) (int, int, string, error) {
+	var result struct {
+		Rank   int
+		Rating int
+		Status string
+	}
+	err := r.breaker.Execute(func() error {
+		tx := r.db.WithContext(ctx).Raw(`
+			SELECT u.rating, u.status,
+				(SELECT COUNT(*) FROM users a WHERE a.status = ? AND a.rating > u.rating) + 1 AS rank
+			FROM users u
+			WHERE u.username = ?`, models.UserActive, username).Scan(&result)
+		if tx.Error != nil {
+			return tx.Error
+		}
+		if tx.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return result.Rank, result.Rating, result.Status, nil
+}
+
+// GetScoreHistory returns the latest limit score events of each user, newest first
+// Users without history are omitted
+func (r *PostgresRepository) GetScoreHistory(ctx context.Context, usernames []string, limit int) (map[string][]models.ScoreEvent, error) {
+	var events []models.ScoreEvent
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Raw(`
+			SELECT id, username, previous_rating, rating, created_at FROM (
+				SELECT *, ROW_NUMBER() OVER (PARTITION BY username ORDER BY created_at DESC, id DESC) AS n
+				FROM score_events
+				WHERE username IN ?
+			) recent
+			WHERE n <= ?
+			ORDER BY username, created_at DESC, id DESC`, usernames, limit).Scan(&events).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[string][]models.ScoreEvent)
+	for _, event := range events {
+		history[event.Username] = append(history[event.Username], event)
+	}
+	return history, nil
+}
+
 // BulkInsertUsers efficiently inserts multiple users
 func (r *PostgresRepository) BulkInsertUsers(ctx context.Context, users []models.User, batchSize int) error {
-	return r.db.WithContext(ctx).CreateInBatches(users, batchSize).Error
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).CreateInBatches(users, batchSize).Error
+	})
 }
 
-// GetTotalUsers returns the total count of users
+// GetTotalUsers returns the number of ranked (active) users
 func (r *PostgresRepository) GetTotalUsers(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Model(&models.User{}).Where("status = ?", models.UserActive).Count(&count).Error
+	})
 	return count, err
 }
 
 // DeleteUser removes a user from the database
 func (r *PostgresRepository) DeleteUser(ctx context.Context, username string) error {
-	return r.db.WithContext(ctx).Where("username = ?", username).Delete(&models.User{}).Error
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Where("username = ?", username).Delete(&models.User{}).Error
+	})
 }
 
 // Ping checks if database is reachable
@@ -80,7 +214,9 @@ func (r *PostgresRepository) Ping(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	return sqlDB.PingContext(ctx)
+	return r.breaker.Execute(func() error {
+		return sqlDB.PingContext(ctx)
+	})
 }
 
 // Close closes the database connection
@@ -94,5 +230,8 @@ func (r *PostgresRepository) Close() error {
 
 // AutoMigrate runs database migrations
 func (r *PostgresRepository) AutoMigrate() error {
-	return r.db.AutoMigrate(&models.User{})
+	if err := r.db.AutoMigrate(&models.User{}, &models.ScoreEvent{}, &models.APIKey{}, &models.ScoreReview{}, &models.AuditEntry{}); err != nil {
+		return err
+	}
+	return r.protectAuditLog()
 }
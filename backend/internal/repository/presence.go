@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PresenceInstancesKey is a sorted set of server instances scored by their last heartbeat
+	PresenceInstancesKey = "leaderboard:presence:instances"
+
+	// presenceInstanceKeyPrefix prefixes each instance's hash of viewer counts per scope
+	presenceInstanceKeyPrefix = "leaderboard:presence:instance:"
+)
+
+// PublishPresence records an instance's viewer counts per scope as a heartbeat
+// The counts expire after ttl, so a crashed instance stops counting without cleanup
+func (r *RedisRepository) PublishPresence(ctx context.Context, instanceID string, counts map[string]int64, ttl time.Duration) error {
+	key := presenceInstanceKeyPrefix + instanceID
+
+	return r.breaker.Execute(func() error {
+		pipe := r.client.TxPipeline()
+		pipe.Del(ctx, key)
+		if len(counts) > 0 {
+			values := make(map[string]interface{}, len(counts))
+			for scope, count := range counts {
+				values[scope] = count
+			}
+			pipe.HSet(ctx, key, values)
+			pipe.Expire(ctx, key, ttl)
+		}
+		pipe.ZAdd(ctx, PresenceInstancesKey, redis.Z{
+			Score:  float64(time.Now().Unix()),
+			Member: instanceID,
+		})
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+}
+
+// RemovePresence drops an instance's counts immediately (on shutdown)
+func (r *RedisRepository) RemovePresence(ctx context.Context, instanceID string) error {
+	return r.breaker.Execute(func() error {
+		pipe := r.client.TxPipeline()
+		pipe.Del(ctx, presenceInstanceKeyPrefix+instanceID)
+		pipe.ZRem(ctx, PresenceInstancesKey, instanceID)
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+}
+
+// GetPresence sums the viewer counts per scope of every instance that sent a heartbeat
+// within ttl, and returns how many instances contributed
+func (r *RedisRepository) GetPresence(ctx context.Context, ttl time.Duration) (map[string]int64, int, error) {
+	cutoff := strconv.FormatInt(time.Now().Add(-ttl).Unix(), 10)
+
+	var instances []string
+	err := r.breaker.Execute(func() error {
+		pipe := r.client.Pipeline()
+		pipe.ZRemRangeByScore(ctx, PresenceInstancesKey, "-inf", "("+cutoff)
+		rangeCmd := pipe.ZRange(ctx, PresenceInstancesKey, 0, -1)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		instances = rangeCmd.Val()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[string]int64)
+	if len(instances) == 0 {
+		return counts, 0, nil
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(instances))
+	err = r.breaker.Execute(func() error {
+		pipe := r.client.Pipeline()
+		for i, instanceID := range instances {
+			cmds[i] = pipe.HGetAll(ctx, presenceInstanceKeyPrefix+instanceID)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, cmd := range cmds {
+		for scope, value := range cmd.Val() {
+			if count, err := strconv.ParseInt(value, 10, 64); err == nil {
+				counts[scope] += count
+			}
+		}
+	}
+	return counts, len(instances), nil
+}
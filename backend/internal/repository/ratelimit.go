@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitKeyPrefix prefixes the token bucket hashes of the rate limiter
+const RateLimitKeyPrefix = "leaderboard:ratelimit:"
+
+// TokenBucket is one bucket consulted by a rate limit check
+// It holds up to Capacity tokens and refills completely every Period
+type TokenBucket struct {
+	Key      string
+	Capacity int
+	Period   time.Duration
+}
+
+// takeTokensScript takes one token from every bucket, or from none if any is empty
+// The clock is Redis's own, so instances with drifting clocks share the same buckets
+// KEYS: buckets   ARGV: capacity and refill rate (tokens per ms) for each bucket
+// Returns the 1-based index of the first empty bucket (0 = allowed) and the ms until
+// every empty bucket has a token again
+var takeTokensScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local denied, retry = 0, 0
+local tokens = {}
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[2 * i - 1])
+  local rate = tonumber(ARGV[2 * i])
+  local state = redis.call('HMGET', key, 'tokens', 'ts')
+  local available = tonumber(state[1]) or capacity
+  local ts = tonumber(state[2]) or now
+  available = math.min(capacity, available + math.max(0, now - ts) * rate)
+  tokens[i] = available
+  if available < 1 then
+    if denied == 0 then denied = i end
+    retry = math.max(retry, math.ceil((1 - available) / rate))
+  end
+end
+if denied == 0 then
+  for i, key in ipairs(KEYS) do
+    local capacity = tonumber(ARGV[2 * i - 1])
+    local rate = tonumber(ARGV[2 * i])
+    redis.call('HSET', key, 'tokens', tokens[i] - 1, 'ts', now)
+    redis.call('PEXPIRE', key, math.ceil(capacity / rate) + 1000)
+  end
+end
+return {denied, retry}
+`)
+
+// TakeTokens atomically takes a token from every bucket
+// It returns the index of the first empty bucket (-1 when the request is allowed) and how
+// long until it can be retried; an empty bucket leaves all buckets untouched
+func (r *RedisRepository) TakeTokens(ctx context.Context, buckets []TokenBucket) (int, time.Duration, error) {
+	keys := make([]string, len(buckets))
+	args := make([]interface{}, 0, 2*len(buckets))
+	for i, bucket := range buckets {
+		keys[i] = bucket.Key
+		rate := float64(bucket.Capacity) / float64(bucket.Period.Milliseconds())
+		args = append(args, bucket.Capacity, strconv.FormatFloat(rate, 'g', -1, 64))
+	}
+
+	var result []int64
+	err := r.breaker.Execute(func() error {
+		var err error
+		result, err = takeTokensScript.Run(ctx, r.client, keys, args...).Int64Slice()
+		return err
+	})
+	if err != nil {
+		return -1, 0, err
+	}
+
+	return int(result[0]) - 1, time.Duration(result[1]) * time.Millisecond, nil
+}
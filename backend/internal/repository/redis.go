@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
+	"backend/internal/breaker"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -19,23 +22,47 @@ const (
 	// VersionKey tracks the global leaderboard version for efficient change detection
 	VersionKey = "leaderboard:version"
 	
+	// ChangesChannel is the Pub/Sub channel every write publishes a ChangeEvent on
+	ChangesChannel = "leaderboard:changes"
+	
 	// TimestampDivisor is used in composite score calculation to prevent precision loss
 	// Using 10^10 ensures timestamp doesn't significantly affect the score
 	TimestampDivisor = 10_000_000_000
 )
 
 // RedisRepository handles all Redis operations
+// Every operation runs through a circuit breaker so a sick Redis fails fast
 type RedisRepository struct {
-	client *redis.Client
+	client  *redis.Client
+	breaker *breaker.Breaker
 }
 
-// NewRedisRepository creates a new Redis repository
+// NewRedisRepository creates a new Redis repository with a default circuit breaker
 func NewRedisRepository(client *redis.Client) *RedisRepository {
 	return &RedisRepository{
-		client: client,
+		client:  client,
+		breaker: NewRedisBreaker(breaker.DefaultConfig()),
 	}
 }
 
+// NewRedisBreaker creates a circuit breaker that ignores cache misses and cancelled requests
+func NewRedisBreaker(cfg breaker.Config) *breaker.Breaker {
+	return breaker.New("redis", cfg, func(err error) bool {
+		return !errors.Is(err, redis.Nil) && !errors.Is(err, context.Canceled)
+	})
+}
+
+// WithBreaker replaces the repository's circuit breaker
+func (r *RedisRepository) WithBreaker(cb *breaker.Breaker) *RedisRepository {
+	r.breaker = cb
+	return r
+}
+
+// Breaker returns the circuit breaker guarding Redis operations
+func (r *RedisRepository) Breaker() *breaker.Breaker {
+	return r.breaker
+}
+
 // ComputeCompositeScore calculates a composite score for consistent tie-breaking
 // Formula: score + (1 - timestamp/10^10)
 // This ensures users who reached the same score earlier have a slightly higher value
@@ -52,35 +79,32 @@ func ExtractBaseScore(compositeScore float64) int {
 }
 
 // UpdateScore updates a user's score in Redis using composite scoring for tie-breaking
-// Also stores metadata in Redis hash for fast retrieval
+// Also stores metadata in Redis hash for fast retrieval, then bumps the version,
+// appends to the changelog and publishes a ChangeEvent - all in one atomic script
 func (r *RedisRepository) UpdateScore(ctx context.Context, username string, rating int) error {
 	timestamp := time.Now().UnixNano()
 	compositeScore := ComputeCompositeScore(rating, timestamp)
 	
-	pipe := r.client.Pipeline()
-	
-	// Update sorted set with composite score
-	pipe.ZAdd(ctx, LeaderboardKey, redis.Z{
-		Score:  compositeScore,
-		Member: username,
+	return r.breaker.Execute(func() error {
+		return updateScoreScript.Run(ctx, r.client,
+			[]string{LeaderboardKey, MetadataKey, VersionKey, ChangelogKey, ModerationKey},
+			ChangelogMaxLen, ChangesChannel, username, rating, "0",
+			strconv.FormatFloat(compositeScore, 'f', -1, 64),
+		).Err()
 	})
-	
-	// Store metadata in hash for fast lookup
-	pipe.HSet(ctx, MetadataKey, username, rating) // Store base score for display
-	
-	// Increment global version for change detection
-	pipe.Incr(ctx, VersionKey)
-	
-	_, err := pipe.Exec(ctx)
-	return err
 }
 
 // GetUserScore retrieves a user's score from Redis metadata hash
 func (r *RedisRepository) GetUserScore(ctx context.Context, username string) (int, error) {
-	scoreStr, err := r.client.HGet(ctx, MetadataKey, username).Result()
+	var scoreStr string
+	err := r.breaker.Execute(func() error {
+		var err error
+		scoreStr, err = r.client.HGet(ctx, MetadataKey, username).Result()
+		return err
+	})
 	if err != nil {
 		if err == redis.Nil {
-			return 0, fmt.Errorf("user not found")
+			return 0, ErrUserNotFound
 		}
 		return 0, err
 	}
@@ -99,7 +123,12 @@ func (r *RedisRepository) GetUserScoreBatch(ctx context.Context, usernames []str
 		return make(map[string]int), nil
 	}
 	
-	results, err := r.client.HMGet(ctx, MetadataKey, usernames...).Result()
+	var results []interface{}
+	err := r.breaker.Execute(func() error {
+		var err error
+		results, err = r.client.HMGet(ctx, MetadataKey, usernames...).Result()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -130,17 +159,27 @@ func (r *RedisRepository) GetUserScoreBatch(ctx context.Context, usernames []str
 // Returns the rank (1-indexed) or error if user not found
 func (r *RedisRepository) GetUserRank(ctx context.Context, username string) (int, error) {
 	// Get the user's composite score from sorted set
-	compositeScore, err := r.client.ZScore(ctx, LeaderboardKey, username).Result()
+	var compositeScore float64
+	err := r.breaker.Execute(func() error {
+		var err error
+		compositeScore, err = r.client.ZScore(ctx, LeaderboardKey, username).Result()
+		return err
+	})
 	if err != nil {
 		if err == redis.Nil {
-			return 0, fmt.Errorf("user not found")
+			return 0, ErrUserNotFound
 		}
 		return 0, err
 	}
 	
 	// Count users with composite score strictly greater than current user
 	// This provides consistent tie-breaking: earlier timestamps rank higher
-	count, err := r.client.ZCount(ctx, LeaderboardKey, fmt.Sprintf("(%f", compositeScore), "+inf").Result()
+	var count int64
+	err = r.breaker.Execute(func() error {
+		var err error
+		count, err = r.client.ZCount(ctx, LeaderboardKey, fmt.Sprintf("(%f", compositeScore), "+inf").Result()
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -151,7 +190,12 @@ func (r *RedisRepository) GetUserRank(ctx context.Context, username string) (int
 
 // GetLeaderboardVersion returns the current global version number
 func (r *RedisRepository) GetLeaderboardVersion(ctx context.Context) (int64, error) {
-	version, err := r.client.Get(ctx, VersionKey).Int64()
+	var version int64
+	err := r.breaker.Execute(func() error {
+		var err error
+		version, err = r.client.Get(ctx, VersionKey).Int64()
+		return err
+	})
 	if err != nil {
 		if err == redis.Nil {
 			return 0, nil // Version not set yet, return 0
@@ -168,7 +212,12 @@ func (r *RedisRepository) GetTopUsers(ctx context.Context, offset, limit int) ([
 	start := int64(offset)
 	stop := int64(offset + limit - 1)
 	
-	results, err := r.client.ZRevRangeWithScores(ctx, LeaderboardKey, start, stop).Result()
+	var results []redis.Z
+	err := r.breaker.Execute(func() error {
+		var err error
+		results, err = r.client.ZRevRangeWithScores(ctx, LeaderboardKey, start, stop).Result()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +232,13 @@ func (r *RedisRepository) GetTopUsers(ctx context.Context, offset, limit int) ([
 
 // GetTotalUsers returns the total number of users in the leaderboard
 func (r *RedisRepository) GetTotalUsers(ctx context.Context) (int64, error) {
-	return r.client.ZCard(ctx, LeaderboardKey).Result()
+	var total int64
+	err := r.breaker.Execute(func() error {
+		var err error
+		total, err = r.client.ZCard(ctx, LeaderboardKey).Result()
+		return err
+	})
+	return total, err
 }
 
 // BulkUpdateScores updates multiple users' scores efficiently using pipeline
@@ -208,16 +263,33 @@ func (r *RedisRepository) BulkUpdateScores(ctx context.Context, users map[string
 		timestamp++
 	}
 	
-	// Increment version once for entire batch
-	pipe.Incr(ctx, VersionKey)
+	// Moderated users only keep their rating; take them back off the board
+	pruneModeratedScript.Eval(ctx, pipe, []string{LeaderboardKey, ModerationKey})
+
+	// Increment version once for entire batch, recorded as a bulk change
+	recordChangeScript.Eval(ctx, pipe,
+		[]string{VersionKey, ChangelogKey},
+		ChangelogMaxLen, ChangesChannel, "", 0, "1",
+	)
 	
-	_, err := pipe.Exec(ctx)
-	return err
+	return r.breaker.Execute(func() error {
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+}
+
+// SubscribeChanges subscribes to the ChangeEvent channel
+// The returned PubSub reconnects automatically; the caller must Close it
+func (r *RedisRepository) SubscribeChanges(ctx context.Context) *redis.PubSub {
+	return r.client.Subscribe(ctx, ChangesChannel)
 }
 
 // Ping checks if Redis is reachable
+// While the breaker is open this fails fast; once half-open it acts as the recovery probe
 func (r *RedisRepository) Ping(ctx context.Context) error {
-	return r.client.Ping(ctx).Err()
+	return r.breaker.Execute(func() error {
+		return r.client.Ping(ctx).Err()
+	})
 }
 
 // Close closes the Redis connection
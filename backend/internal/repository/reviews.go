@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateReview queues a suspicious score update for review
+func (r *PostgresRepository) CreateReview(ctx context.Context, review *models.ScoreReview) error {
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Create(review).Error
+	})
+}
+
+// ListReviews retrieves a page of reviews, oldest first, and the total count
+// An empty status lists reviews in every state
+func (r *PostgresRepository) ListReviews(ctx context.Context, status string, offset, limit int) ([]models.ScoreReview, int64, error) {
+	var reviews []models.ScoreReview
+	var total int64
+	err := r.breaker.Execute(func() error {
+		query := r.db.WithContext(ctx).Model(&models.ScoreReview{})
+		if status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if err := query.Count(&total).Error; err != nil {
+			return err
+		}
+		return query.Order("id ASC").Offset(offset).Limit(limit).Find(&reviews).Error
+	})
+	return reviews, total, err
+}
+
+// ResolveReview moves a pending review to status and returns it
+// Returns ErrReviewNotFound if the review does not exist or was already resolved,
+// so concurrent reviewers cannot both resolve it
+func (r *PostgresRepository) ResolveReview(ctx context.Context, id uint, status string, reviewerKeyID uint, note string) (*models.ScoreReview, error) {
+	var review models.ScoreReview
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			updates := map[string]interface{}{
+				"status":      status,
+				"review_note": note,
+				"reviewed_at": time.Now(),
+			}
+			if reviewerKeyID != 0 {
+				updates["reviewed_by"] = reviewerKeyID
+			}
+
+			result := tx.Model(&models.ScoreReview{}).
+				Where("id = ? AND status = ?", id, models.ReviewPending).
+				Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrReviewNotFound
+			}
+			return tx.First(&review, id).Error
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// ReopenReview puts a review resolved to status back in the queue, clearing the resolution
+// Used when the resolution could not be applied, so the review can be resolved again
+func (r *PostgresRepository) ReopenReview(ctx context.Context, id uint, status string) error {
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Model(&models.ScoreReview{}).
+			Where("id = ? AND status = ?", id, status).
+			Updates(map[string]interface{}{
+				"status":      models.ReviewPending,
+				"review_note": "",
+				"reviewed_at": nil,
+				"reviewed_by": nil,
+			}).Error
+	})
+}
+
+// MarkReviewReverted records that the update of a rejected review was undone
+func (r *PostgresRepository) MarkReviewReverted(ctx context.Context, id uint) error {
+	return r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Model(&models.ScoreReview{}).
+			Where("id = ?", id).
+			Update("reverted", true).Error
+	})
+}
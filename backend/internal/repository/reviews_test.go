@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestResolveReview(t *testing.T) {
+	ctx := context.Background()
+	repo, db := openTestPostgres(t)
+
+	review := models.ScoreReview{
+		Username: "alice",
+		Rating:   4000,
+		Action:   models.ReviewActionBlock,
+		Reasons:  "max_jump",
+		Status:   models.ReviewPending,
+	}
+	if err := repo.CreateReview(ctx, &review); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := repo.ResolveReview(ctx, review.ID, models.ReviewApproved, 7, "looks fine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Status != models.ReviewApproved || resolved.ReviewedBy == nil || *resolved.ReviewedBy != 7 ||
+		resolved.ReviewNote != "looks fine" || resolved.ReviewedAt == nil {
+		t.Fatalf("resolved review = %+v", resolved)
+	}
+
+	// Only one reviewer can claim it
+	if _, err := repo.ResolveReview(ctx, review.ID, models.ReviewRejected, 8, ""); !errors.Is(err, ErrReviewNotFound) {
+		t.Fatalf("second resolve: err %v, want ErrReviewNotFound", err)
+	}
+
+	// Reopening only applies to the status the review was resolved to
+	if err := repo.ReopenReview(ctx, review.ID, models.ReviewRejected); err != nil {
+		t.Fatal(err)
+	}
+	var stored models.ScoreReview
+	if err := db.First(&stored, review.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ReviewApproved {
+		t.Fatalf("status = %q after reopening a rejection, want approved", stored.Status)
+	}
+
+	if err := repo.ReopenReview(ctx, review.ID, models.ReviewApproved); err != nil {
+		t.Fatal(err)
+	}
+	stored = models.ScoreReview{}
+	if err := db.First(&stored, review.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ReviewPending || stored.ReviewedBy != nil || stored.ReviewedAt != nil || stored.ReviewNote != "" {
+		t.Fatalf("reopened review = %+v, want pending with no resolution", stored)
+	}
+
+	// Reopened reviews can be resolved again
+	if _, err := repo.ResolveReview(ctx, review.ID, models.ReviewRejected, 8, ""); err != nil {
+		t.Fatalf("resolve after reopening: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rollbackBatchSize bounds the rows written per statement by a rollback
+const rollbackBatchSize = 500
+
+// SetRating overrides an existing user's rating and records the change with reason
+// audited builds the audit entries of the change, written in the same transaction.
+// Returns ErrUserNotFound if the user does not exist
+func (r *PostgresRepository) SetRating(ctx context.Context, username string, rating int, apiKeyID uint, reason string, audited func(event *models.ScoreEvent) []models.AuditEntry) (*models.ScoreEvent, error) {
+	var event models.ScoreEvent
+	err := r.breaker.Execute(func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var previous []int
+			if err := tx.Model(&models.User{}).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("username = ?", username).
+				Pluck("rating", &previous).Error; err != nil {
+				return err
+			}
+			if len(previous) == 0 {
+				return ErrUserNotFound
+			}
+
+			if err := tx.Model(&models.User{}).
+				Where("username = ?", username).
+				Update("rating", rating).Error; err != nil {
+				return err
+			}
+
+			event = models.ScoreEvent{
+				Username:       username,
+				PreviousRating: &previous[0],
+				Rating:         rating,
+				Reason:         reason,
+			}
+			if apiKeyID != 0 {
+				event.APIKeyID = &apiKeyID
+			}
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+
+			if audited == nil {
+				return nil
+			}
+			return createAuditEntries(tx, audited(&event))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// RollbackScores reverts score updates made before to (and from from, when set), of the
+// given users or of everyone when usernames is empty
+// Each matched user goes back to their rating before their first matched update, so later
+// updates are reverted too. Users created by a matched update are deleted. Restored
+// ratings are recorded in the score history with reason, and audited builds the audit
+// entries of the changes, written in the same transaction. With dryRun nothing is written.
+func (r *PostgresRepository) RollbackScores(ctx context.Context, usernames []string, from *time.Time, to time.Time, apiKeyID uint, reason string, dryRun bool, audited func(changes []models.RollbackChange) []models.AuditEntry) ([]models.RollbackChange, error) {
+	var changes []models.RollbackChange
+	err := r.breaker.Execute(func() error {
+		changes = nil
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			matched := tx.Model(&models.ScoreEvent{}).Where("created_at < ?", to)
+			if from != nil {
+				matched = matched.Where("created_at >= ?", *from)
+			}
+			if len(usernames) > 0 {
+				matched = matched.Where("username IN ?", usernames)
+			}
+
+			// The first matched update of each user holds the rating to go back to
+			var firsts []struct {
+				Username       string
+				PreviousRating *int
+			}
+			if err := matched.Session(&gorm.Session{}).
+				Select("DISTINCT ON (username) username, previous_rating").
+				Order("username, created_at, id").
+				Scan(&firsts).Error; err != nil {
+				return err
+			}
+			if len(firsts) == 0 {
+				return nil
+			}
+
+			// Lock the users so concurrent upserts wait for the rollback
+			var users []models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("username", "rating").
+				Where("username IN (?)", matched.Session(&gorm.Session{}).Distinct("username")).
+				Find(&users).Error; err != nil {
+				return err
+			}
+			current := make(map[string]int, len(users))
+			for _, user := range users {
+				current[user.Username] = user.Rating
+			}
+
+			for _, first := range firsts {
+				rating, exists := current[first.Username]
+				if !exists {
+					continue // Deleted since
+				}
+				if first.PreviousRating != nil && *first.PreviousRating == rating {
+					continue // Already back where it was
+				}
+				changes = append(changes, models.RollbackChange{
+					Username: first.Username,
+					From:     rating,
+					To:       first.PreviousRating,
+				})
+			}
+			if dryRun || len(changes) == 0 {
+				return nil
+			}
+
+			var removed []string
+			events := make([]models.ScoreEvent, 0, len(changes))
+			for _, change := range changes {
+				if change.To == nil {
+					removed = append(removed, change.Username)
+					continue
+				}
+				if err := tx.Model(&models.User{}).
+					Where("username = ?", change.Username).
+					Update("rating", *change.To).Error; err != nil {
+					return err
+				}
+
+				previous := change.From
+				event := models.ScoreEvent{
+					Username:       change.Username,
+					PreviousRating: &previous,
+					Rating:         *change.To,
+					Reason:         reason,
+				}
+				if apiKeyID != 0 {
+					event.APIKeyID = &apiKeyID
+				}
+				events = append(events, event)
+			}
+
+			for start := 0; start < len(removed); start += rollbackBatchSize {
+				end := min(start+rollbackBatchSize, len(removed))
+				if err := tx.Where("username IN ?", removed[start:end]).Delete(&models.User{}).Error; err != nil {
+					return err
+				}
+			}
+			if len(events) > 0 {
+				if err := tx.CreateInBatches(&events, rollbackBatchSize).Error; err != nil {
+					return err
+				}
+			}
+
+			if audited == nil {
+				return nil
+			}
+			return createAuditEntries(tx, audited(changes))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ApplyRollback writes restored ratings and removes deleted users in one transaction,
+// recorded as a single bulk change so clients refetch
+func (r *RedisRepository) ApplyRollback(ctx context.Context, ratings map[string]int, removed []string) error {
+	if len(ratings) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	pipe := r.client.TxPipeline()
+	timestamp := time.Now().UnixNano()
+	for username, rating := range ratings {
+		pipe.ZAdd(ctx, LeaderboardKey, redis.Z{
+			Score:  ComputeCompositeScore(rating, timestamp),
+			Member: username,
+		})
+		pipe.HSet(ctx, MetadataKey, username, rating)
+		timestamp++
+	}
+	if len(removed) > 0 {
+		members := make([]interface{}, len(removed))
+		for i, username := range removed {
+			members[i] = username
+		}
+		pipe.ZRem(ctx, LeaderboardKey, members...)
+		pipe.HDel(ctx, MetadataKey, removed...)
+		pipe.HDel(ctx, ModerationKey, removed...)
+	}
+
+	// Restored users that are moderated stay off the board
+	pruneModeratedScript.Eval(ctx, pipe, []string{LeaderboardKey, ModerationKey})
+	recordChangeScript.Eval(ctx, pipe,
+		[]string{VersionKey, ChangelogKey},
+		ChangelogMaxLen, ChangesChannel, "", 0, "1",
+	)
+
+	return r.breaker.Execute(func() error {
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+}
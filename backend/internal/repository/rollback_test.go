@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestPostgres returns a repository on a fresh schema of the database at
+// TEST_DATABASE_URL, dropped when the test ends; the test is skipped without one
+func openTestPostgres(t *testing.T) (*PostgresRepository, *gorm.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, so the search_path below applies to every query
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	schema := fmt.Sprintf("rollback_test_%d", time.Now().UnixNano())
+	if err := db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") })
+	if err := db.Exec("SET search_path TO " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewPostgresRepository(db)
+	if err := repo.AutoMigrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return repo, db
+}
+
+// Times of the seeded score updates
+var (
+	t1 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	t2 = t1.Add(time.Hour)
+	t3 = t1.Add(2 * time.Hour)
+	t4 = t1.Add(3 * time.Hour)
+)
+
+type seedUpdate struct {
+	at       time.Time
+	previous *int // nil when the update created the user
+	rating   int
+}
+
+func ratingPtr(n int) *int { return &n }
+
+// seedRollbackUsers creates users whose ratings are the result of their updates:
+//
+//	alice: 800 -> 1000 (t1), -> 1500 (t2), -> 2000 (t3)
+//	bob:   500 -> 600 (t1), -> 700 (t3)
+//	carol: created at 1200 (t2)
+//	dave:  1000 -> 1500 (t2), back to 1000 (t3)
+func seedRollbackUsers(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	seed := map[string][]seedUpdate{
+		"alice": {{t1, ratingPtr(800), 1000}, {t2, ratingPtr(1000), 1500}, {t3, ratingPtr(1500), 2000}},
+		"bob":   {{t1, ratingPtr(500), 600}, {t3, ratingPtr(600), 700}},
+		"carol": {{t2, nil, 1200}},
+		"dave":  {{t2, ratingPtr(1000), 1500}, {t3, ratingPtr(1500), 1000}},
+	}
+	for username, updates := range seed {
+		user := models.User{Username: username, Rating: updates[len(updates)-1].rating, CreatedAt: updates[0].at}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, update := range updates {
+			event := models.ScoreEvent{
+				Username:       username,
+				PreviousRating: update.previous,
+				Rating:         update.rating,
+				CreatedAt:      update.at,
+			}
+			if err := db.Create(&event).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// currentRatings returns the rating of every user
+func currentRatings(t *testing.T, db *gorm.DB) map[string]int {
+	t.Helper()
+	var users []models.User
+	if err := db.Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	ratings := make(map[string]int, len(users))
+	for _, user := range users {
+		ratings[user.Username] = user.Rating
+	}
+	return ratings
+}
+
+func countRows(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(model).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestRollbackScores(t *testing.T) {
+	tests := []struct {
+		name      string
+		usernames []string
+		from      *time.Time
+		to        time.Time
+		changes   []models.RollbackChange
+		ratings   map[string]int // Every user left afterwards
+	}{
+		{
+			name:      "user only",
+			usernames: []string{"alice"},
+			to:        t4,
+			changes:   []models.RollbackChange{{Username: "alice", From: 2000, To: ratingPtr(800)}},
+			ratings:   map[string]int{"alice": 800, "bob": 700, "carol": 1200, "dave": 1000},
+		},
+		{
+			// carol was created in the window, so she is removed; dave is already back
+			name: "window only",
+			from: &t2,
+			to:   t3,
+			changes: []models.RollbackChange{
+				{Username: "alice", From: 2000, To: ratingPtr(1000)},
+				{Username: "carol", From: 1200, To: nil},
+			},
+			ratings: map[string]int{"alice": 1000, "bob": 700, "dave": 1000},
+		},
+		{
+			name:      "users and window",
+			usernames: []string{"bob", "carol"},
+			from:      &t2,
+			to:        t4,
+			changes: []models.RollbackChange{
+				{Username: "bob", From: 700, To: ratingPtr(600)},
+				{Username: "carol", From: 1200, To: nil},
+			},
+			ratings: map[string]int{"alice": 2000, "bob": 600, "dave": 1000},
+		},
+		{
+			name:      "already back where it was",
+			usernames: []string{"dave"},
+			to:        t4,
+			changes:   nil,
+			ratings:   map[string]int{"alice": 2000, "bob": 700, "carol": 1200, "dave": 1000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, db := openTestPostgres(t)
+			seedRollbackUsers(t, db)
+			events := countRows(t, db, &models.ScoreEvent{})
+
+			var audited []models.RollbackChange
+			changes, err := repo.RollbackScores(context.Background(), tt.usernames, tt.from, tt.to, 0, "test rollback", false,
+				func(changes []models.RollbackChange) []models.AuditEntry {
+					audited = changes
+					return []models.AuditEntry{{Actor: "system", Action: "scores.rollback"}}
+				})
+			if err != nil {
+				t.Fatalf("RollbackScores: %v", err)
+			}
+
+			sort.Slice(changes, func(i, j int) bool { return changes[i].Username < changes[j].Username })
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("changes = %+v, want %+v", changes, tt.changes)
+			}
+			if got := currentRatings(t, db); !reflect.DeepEqual(got, tt.ratings) {
+				t.Errorf("ratings = %v, want %v", got, tt.ratings)
+			}
+
+			// Each restored user gets a score event with the reason; removed users get none
+			restored := 0
+			for _, change := range tt.changes {
+				if change.To != nil {
+					restored++
+				}
+			}
+			var written []models.ScoreEvent
+			if err := db.Where("reason = ?", "test rollback").Find(&written).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(written) != restored || countRows(t, db, &models.ScoreEvent{}) != events+int64(restored) {
+				t.Errorf("wrote %d score events, want %d", len(written), restored)
+			}
+
+			// Audit rows are written with the changes, and only when there are some
+			wantAudit := int64(0)
+			if len(tt.changes) > 0 {
+				wantAudit = 1
+				if len(audited) != len(tt.changes) {
+					t.Errorf("audited %d changes, want %d", len(audited), len(tt.changes))
+				}
+			}
+			if got := countRows(t, db, &models.AuditEntry{}); got != wantAudit {
+				t.Errorf("wrote %d audit entries, want %d", got, wantAudit)
+			}
+		})
+	}
+}
+
+func TestRollbackScoresDryRun(t *testing.T) {
+	repo, db := openTestPostgres(t)
+	seedRollbackUsers(t, db)
+	before := currentRatings(t, db)
+	events := countRows(t, db, &models.ScoreEvent{})
+
+	changes, err := repo.RollbackScores(context.Background(), nil, &t2, t3, 0, "dry run", true,
+		func(changes []models.RollbackChange) []models.AuditEntry {
+			t.Error("dry runs must not be audited")
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("RollbackScores: %v", err)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Username < changes[j].Username })
+	want := []models.RollbackChange{
+		{Username: "alice", From: 2000, To: ratingPtr(1000)},
+		{Username: "carol", From: 1200, To: nil},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+
+	if after := currentRatings(t, db); !reflect.DeepEqual(after, before) {
+		t.Errorf("ratings changed to %v, want %v", after, before)
+	}
+	if got := countRows(t, db, &models.ScoreEvent{}); got != events {
+		t.Errorf("score events = %d, want %d", got, events)
+	}
+	if got := countRows(t, db, &models.AuditEntry{}); got != 0 {
+		t.Errorf("audit entries = %d, want 0", got)
+	}
+}
@@ -0,0 +1,14 @@
+package service
+
+import "backend/internal/audit"
+
+// WithAudit records the service's administrative actions in the audit log
+func (s *LeaderboardService) WithAudit(log *audit.Log) *LeaderboardService {
+	s.audit = log
+	return s
+}
+
+// Audit returns the audit log (nil when actions are not audited)
+func (s *LeaderboardService) Audit() *audit.Log {
+	return s.audit
+}
@@ -2,9 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"sort"
+	"sync/atomic"
 
+	"backend/internal/anticheat"
+	"backend/internal/audit"
+	"backend/internal/breaker"
 	"backend/internal/models"
 	"backend/internal/repository"
 	"backend/internal/worker"
@@ -12,12 +20,30 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// maxChangesPerRequest caps the changelog entries read for one change feed request
+const maxChangesPerRequest = 1000
+
+// ErrChangeFeedUnavailable is returned while degraded, since the changelog lives in Redis
+var ErrChangeFeedUnavailable = errors.New("change feed unavailable while Redis is down")
+
 // LeaderboardService handles business logic for the leaderboard
 type LeaderboardService struct {
 	redisRepo    *repository.RedisRepository
 	postgresRepo *repository.PostgresRepository
 	workerPool   *worker.WorkerPool
 	redisClient  *redis.Client
+
+	// degraded is set while Redis is unreachable and reads are served from PostgreSQL
+	degraded atomic.Bool
+
+	// replay buffers writes accepted in degraded mode until Redis recovers
+	replay *replayQueue
+
+	// antiCheat checks client submissions (nil = unchecked)
+	antiCheat *anticheat.Engine
+
+	// audit records administrative actions (nil = not audited)
+	audit *audit.Log
 }
 
 // NewLeaderboardService creates a new leaderboard service
@@ -32,12 +58,44 @@ func NewLeaderboardService(
 		postgresRepo: postgresRepo,
 		workerPool:   workerPool,
 		redisClient:  redisClient,
+		replay:       newReplayQueue(),
 	}
 }
 
+// IsDegraded reports whether the service is serving from PostgreSQL because Redis is unavailable
+func (s *LeaderboardService) IsDegraded() bool {
+	return s.degraded.Load()
+}
+
+// markDegraded switches the service to the PostgreSQL read path after a Redis failure
+// Only connectivity failures count as outages; other errors (the caller going away,
+// missing users, script errors, WRONGTYPE) are left for the caller to return unchanged
+func (s *LeaderboardService) markDegraded(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || !isConnectivityError(err) {
+		return false
+	}
+	if s.degraded.CompareAndSwap(false, true) {
+		log.Printf("⚠️ Redis unavailable, entering degraded mode: %v", err)
+	}
+	return true
+}
+
+// isConnectivityError reports whether err means Redis could not be reached: the breaker
+// is open, the connection failed or was dropped, or no pooled connection was free in time
+func isConnectivityError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, breaker.ErrOpen) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrPoolTimeout) ||
+		errors.Is(err, redis.ErrClosed)
+}
+
 // UpdateScore updates a user's score using write-through cache strategy with worker pool
 // Redis is updated synchronously, PostgreSQL via worker pool (non-blocking with backpressure)
-func (s *LeaderboardService) UpdateScore(ctx context.Context, username string, rating int) error {
+// apiKeyID attributes the write in score history (0 for internal writes such as the simulator)
+func (s *LeaderboardService) UpdateScore(ctx context.Context, username string, rating int, apiKeyID uint) error {
 	// Enforce score constraints (min: 100, max: 5000)
 	if rating < 100 {
 		rating = 100
@@ -46,9 +104,16 @@ func (s *LeaderboardService) UpdateScore(ctx context.Context, username string, r
 		rating = 5000
 	}
 
+	if s.degraded.Load() {
+		return s.updateScoreDegraded(ctx, username, rating, apiKeyID)
+	}
+
 	// Step 1: Update Redis synchronously (critical path for low latency)
 	// This also increments the version counter automatically
 	if err := s.redisRepo.UpdateScore(ctx, username, rating); err != nil {
+		if s.markDegraded(ctx, err) {
+			return s.updateScoreDegraded(ctx, username, rating, apiKeyID)
+		}
 		return fmt.Errorf("failed to update Redis: %w", err)
 	}
 
@@ -56,6 +121,7 @@ func (s *LeaderboardService) UpdateScore(ctx context.Context, username string, r
 	task := worker.ScoreUpdateTask{
 		Username: username,
 		Rating:   rating,
+		APIKeyID: apiKeyID,
 	}
 	
 	if err := s.workerPool.Submit(task); err != nil {
@@ -63,11 +129,22 @@ func (s *LeaderboardService) UpdateScore(ctx context.Context, username string, r
 		// Error is already logged by the worker pool
 	}
 
-	// Note: No Pub/Sub broadcasting needed
-	// WebSocket hub polls the version counter every 2 seconds
-	// and broadcasts version updates to clients
-	// This eliminates the "request storm" problem
+	// Note: The Redis write publishes a ChangeEvent on ChangesChannel
+	// Every instance's WebSocket hub coalesces these into one VERSION_UPDATE
+	// per 50ms window, which still avoids the "request storm" problem
+
+	return nil
+}
+
+// updateScoreDegraded persists a score while Redis is unavailable
+// PostgreSQL is written synchronously because it serves reads in degraded mode,
+// and the write is queued for replay to Redis once it recovers
+func (s *LeaderboardService) updateScoreDegraded(ctx context.Context, username string, rating int, apiKeyID uint) error {
+	if err := s.postgresRepo.UpsertUser(ctx, username, rating, apiKeyID); err != nil {
+		return fmt.Errorf("failed to update PostgreSQL in degraded mode: %w", err)
+	}
 
+	s.queueReplay(username, rating)
 	return nil
 }
 
@@ -81,20 +158,56 @@ func (s *LeaderboardService) GetLeaderboard(ctx context.Context, offset, limit i
 		limit = 50
 	}
 
+	if s.degraded.Load() {
+		return s.getLeaderboardFromPostgres(ctx, offset, limit)
+	}
+
 	// Get users from Redis
 	users, err := s.redisRepo.GetTopUsers(ctx, offset, limit)
 	if err != nil {
+		if s.markDegraded(ctx, err) {
+			return s.getLeaderboardFromPostgres(ctx, offset, limit)
+		}
 		return nil, fmt.Errorf("failed to get top users: %w", err)
 	}
 
 	// Get total count
 	total, err := s.redisRepo.GetTotalUsers(ctx)
 	if err != nil {
+		if s.markDegraded(ctx, err) {
+			return s.getLeaderboardFromPostgres(ctx, offset, limit)
+		}
 		return nil, fmt.Errorf("failed to get total users: %w", err)
 	}
 
 	// Apply tie-aware ranking logic (1224)
-	entries := s.applyTieAwareRanking(users, offset)
+	entries, err := s.applyTieAwareRanking(ctx, users, offset)
+	if err != nil {
+		if s.markDegraded(ctx, err) {
+			return s.getLeaderboardFromPostgres(ctx, offset, limit)
+		}
+		return nil, fmt.Errorf("failed to rank users: %w", err)
+	}
+
+	return &models.LeaderboardResponse{
+		Data:   entries,
+		Offset: offset,
+		Limit:  limit,
+		Total:  total,
+	}, nil
+}
+
+// getLeaderboardFromPostgres serves a leaderboard page from PostgreSQL (degraded read path)
+func (s *LeaderboardService) getLeaderboardFromPostgres(ctx context.Context, offset, limit int) (*models.LeaderboardResponse, error) {
+	entries, err := s.postgresRepo.GetLeaderboardPage(ctx, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard from PostgreSQL: %w", err)
+	}
+
+	total, err := s.postgresRepo.GetTotalUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total users from PostgreSQL: %w", err)
+	}
 
 	return &models.LeaderboardResponse{
 		Data:   entries,
@@ -106,15 +219,25 @@ func (s *LeaderboardService) GetLeaderboard(ctx context.Context, offset, limit i
 
 // SearchUser searches for a user and returns their rank
 func (s *LeaderboardService) SearchUser(ctx context.Context, username string) (*models.SearchResponse, error) {
+	if s.degraded.Load() {
+		return s.searchUserInPostgres(ctx, username)
+	}
+
 	// Get user's rank
 	rank, err := s.redisRepo.GetUserRank(ctx, username)
 	if err != nil {
+		if s.markDegraded(ctx, err) {
+			return s.searchUserInPostgres(ctx, username)
+		}
 		return nil, fmt.Errorf("failed to get user rank: %w", err)
 	}
 
 	// Get user's score
 	rating, err := s.redisRepo.GetUserScore(ctx, username)
 	if err != nil {
+		if s.markDegraded(ctx, err) {
+			return s.searchUserInPostgres(ctx, username)
+		}
 		return nil, fmt.Errorf("failed to get user score: %w", err)
 	}
 
@@ -125,14 +248,181 @@ func (s *LeaderboardService) SearchUser(ctx context.Context, username string) (*
 	}, nil
 }
 
+// GetChangesSince returns the users whose ratings changed after version since
+// Each user appears once with their latest rating and current rank. Clients must
+// resync when the changelog no longer covers since or when a bulk write occurred.
+func (s *LeaderboardService) GetChangesSince(ctx context.Context, since int64) (*models.ChangesResponse, error) {
+	if s.degraded.Load() {
+		return nil, ErrChangeFeedUnavailable
+	}
+
+	events, complete, err := s.redisRepo.GetChangesSince(ctx, since, maxChangesPerRequest)
+	if err != nil {
+		if s.markDegraded(ctx, err) {
+			return nil, ErrChangeFeedUnavailable
+		}
+		return nil, fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	response := &models.ChangesResponse{
+		Since:   since,
+		Version: since,
+		Changes: []models.ChangeEntry{},
+	}
+	if !complete {
+		response.ResyncRequired = true
+		return response, nil
+	}
+
+	// Keep the latest change per user, ordered by the version it happened at
+	latest := make(map[string]models.ChangeEvent, len(events))
+	usernames := make([]string, 0, len(events))
+	for _, event := range events {
+		response.Version = event.Version
+		if event.Bulk {
+			response.ResyncRequired = true
+			response.Changes = []models.ChangeEntry{}
+			return response, nil
+		}
+		if _, seen := latest[event.Username]; !seen {
+			usernames = append(usernames, event.Username)
+		}
+		latest[event.Username] = event
+	}
+	response.HasMore = len(events) == maxChangesPerRequest
+
+	positions, err := s.redisRepo.GetUserPositions(ctx, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ranks for changed users: %w", err)
+	}
+
+	for _, username := range usernames {
+		rank, ok := positions[username]
+		if !ok {
+			continue // User was removed after the change
+		}
+		event := latest[username]
+		response.Changes = append(response.Changes, models.ChangeEntry{
+			Username: username,
+			Rating:   event.Rating,
+			Rank:     rank,
+			Version:  event.Version,
+		})
+	}
+
+	sort.Slice(response.Changes, func(i, j int) bool {
+		return response.Changes[i].Version < response.Changes[j].Version
+	})
+
+	return response, nil
+}
+
+// GetUserEntries returns the current rank and rating of each user, in a single round trip
+// while Redis is healthy. Users not on the leaderboard are omitted.
+func (s *LeaderboardService) GetUserEntries(ctx context.Context, usernames []string) (map[string]models.LeaderboardEntry, error) {
+	if !s.degraded.Load() {
+		positions, err := s.redisRepo.GetUserPositions(ctx, usernames)
+		if err == nil {
+			var ratings map[string]int
+			ratings, err = s.redisRepo.GetUserScoreBatch(ctx, usernames)
+			if err == nil {
+				entries := make(map[string]models.LeaderboardEntry, len(positions))
+				for username, rank := range positions {
+					entries[username] = models.LeaderboardEntry{
+						Rank:     rank,
+						Username: username,
+						Rating:   ratings[username],
+					}
+				}
+				return entries, nil
+			}
+		}
+		if !s.markDegraded(ctx, err) {
+			return nil, fmt.Errorf("failed to get user ranks: %w", err)
+		}
+	}
+
+	entries := make(map[string]models.LeaderboardEntry, len(usernames))
+	for _, username := range usernames {
+		user, err := s.searchUserInPostgres(ctx, username)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[username] = models.LeaderboardEntry{
+			Rank:     user.GlobalRank,
+			Username: username,
+			Rating:   user.Rating,
+		}
+	}
+	return entries, nil
+}
+
+// GetUserRatings returns the current rating of each user with a single HMGET while
+// Redis is healthy. Users not on the leaderboard are omitted.
+func (s *LeaderboardService) GetUserRatings(ctx context.Context, usernames []string) (map[string]int, error) {
+	if !s.degraded.Load() {
+		ratings, err := s.redisRepo.GetUserScoreBatch(ctx, usernames)
+		if err == nil {
+			return ratings, nil
+		}
+		if !s.markDegraded(ctx, err) {
+			return nil, fmt.Errorf("failed to get user ratings: %w", err)
+		}
+	}
+
+	entries, err := s.GetUserEntries(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+	ratings := make(map[string]int, len(entries))
+	for username, entry := range entries {
+		ratings[username] = entry.Rating
+	}
+	return ratings, nil
+}
+
+// GetScoreHistory returns the latest limit rating changes of each user, newest first
+// History lives in PostgreSQL, so it is available in degraded mode too
+func (s *LeaderboardService) GetScoreHistory(ctx context.Context, usernames []string, limit int) (map[string][]models.ScoreEvent, error) {
+	if len(usernames) == 0 {
+		return map[string][]models.ScoreEvent{}, nil
+	}
+	history, err := s.postgresRepo.GetScoreHistory(ctx, usernames, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get score history: %w", err)
+	}
+	return history, nil
+}
+
+// searchUserInPostgres looks up a user's rank in PostgreSQL (degraded read path)
+func (s *LeaderboardService) searchUserInPostgres(ctx context.Context, username string) (*models.SearchResponse, error) {
+	rank, rating, status, err := s.postgresRepo.GetUserRank(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user rank from PostgreSQL: %w", err)
+	}
+	if status != models.UserActive {
+		// Moderated users are not on the public leaderboard
+		return nil, fmt.Errorf("failed to get user rank from PostgreSQL: %w", repository.ErrUserNotFound)
+	}
+
+	return &models.SearchResponse{
+		GlobalRank: rank,
+		Username:   username,
+		Rating:     rating,
+	}, nil
+}
+
 // applyTieAwareRanking applies the 1224 ranking system
 // Users with the same score get the same rank
 // The next rank is offset by the number of users sharing the previous rank
-func (s *LeaderboardService) applyTieAwareRanking(users []redis.Z, offset int) []models.LeaderboardEntry {
+func (s *LeaderboardService) applyTieAwareRanking(ctx context.Context, users []redis.Z, offset int) ([]models.LeaderboardEntry, error) {
 	entries := make([]models.LeaderboardEntry, 0, len(users))
 	
 	if len(users) == 0 {
-		return entries
+		return entries, nil
 	}
 
 	// Fetch actual ratings for all users in batch
@@ -141,12 +431,10 @@ func (s *LeaderboardService) applyTieAwareRanking(users []redis.Z, offset int) [
 		usernames[i] = user.Member.(string)
 	}
 	
-	ctx := context.Background()
 	ratings, err := s.redisRepo.GetUserScoreBatch(ctx, usernames)
 	if err != nil {
 		log.Printf("Failed to fetch ratings for tie-aware ranking: %v", err)
-		// Fallback to empty entries on error
-		return entries
+		return nil, err
 	}
 
 	// Start rank at offset + 1 (1-indexed)
@@ -179,7 +467,7 @@ func (s *LeaderboardService) applyTieAwareRanking(users []redis.Z, offset int) [
 		})
 	}
 
-	return entries
+	return entries, nil
 }
 
 // GetAllUsers retrieves all users from PostgreSQL (used by simulator)
@@ -202,8 +490,17 @@ func (s *LeaderboardService) SyncRedisFromPostgres(ctx context.Context) error {
 
 	// Build map for bulk update
 	userMap := make(map[string]int, len(users))
+	moderated := make(map[string]string)
 	for _, user := range users {
 		userMap[user.Username] = user.Rating
+		if user.Status != models.UserActive {
+			moderated[user.Username] = user.Status
+		}
+	}
+
+	// Moderation first, so the bulk update keeps moderated users off the board
+	if err := s.redisRepo.ReplaceModeration(ctx, moderated); err != nil {
+		return fmt.Errorf("failed to sync moderation to Redis: %w", err)
 	}
 
 	// Bulk update Redis
@@ -211,14 +508,21 @@ func (s *LeaderboardService) SyncRedisFromPostgres(ctx context.Context) error {
 		return fmt.Errorf("failed to sync to Redis: %w", err)
 	}
 
+	s.audit.Record(ctx, audit.Entry{
+		Action: audit.ActionSync,
+		After:  map[string]int{"users": len(users), "moderated": len(moderated)},
+	})
 	log.Printf("Successfully synced %d users to Redis", len(users))
 	return nil
 }
 
 // HealthCheck checks the health of both Redis and PostgreSQL
+// A Redis outage is not fatal: the service keeps serving in degraded mode
 func (s *LeaderboardService) HealthCheck(ctx context.Context) error {
 	if err := s.redisRepo.Ping(ctx); err != nil {
-		return fmt.Errorf("Redis health check failed: %w", err)
+		if !s.markDegraded(ctx, err) {
+			return fmt.Errorf("Redis health check failed: %w", err)
+		}
 	}
 
 	if err := s.postgresRepo.Ping(ctx); err != nil {
@@ -227,3 +531,11 @@ func (s *LeaderboardService) HealthCheck(ctx context.Context) error {
 
 	return nil
 }
+
+// BreakerStates returns a snapshot of the circuit breakers guarding Redis and PostgreSQL
+func (s *LeaderboardService) BreakerStates() map[string]breaker.Snapshot {
+	return map[string]breaker.Snapshot{
+		"redis":    s.redisRepo.Breaker().Snapshot(),
+		"postgres": s.postgresRepo.Breaker().Snapshot(),
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"backend/internal/breaker"
+	"backend/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMarkDegraded(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		degraded bool
+	}{
+		{"breaker open", breaker.ErrOpen, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"connection dropped", fmt.Errorf("read: %w", io.EOF), true},
+		{"pool timeout", redis.ErrPoolTimeout, true},
+		{"client closed", redis.ErrClosed, true},
+		{"missing user", repository.ErrUserNotFound, false},
+		{"script error", errors.New("ERR Error running script"), false},
+		{"wrong type", errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &LeaderboardService{}
+			if got := s.markDegraded(context.Background(), tt.err); got != tt.degraded || s.degraded.Load() != tt.degraded {
+				t.Errorf("markDegraded = %v (degraded %v), want %v", got, s.degraded.Load(), tt.degraded)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := &LeaderboardService{}
+	if s.markDegraded(ctx, breaker.ErrOpen) {
+		t.Error("a cancelled caller must not degrade the service")
+	}
+}
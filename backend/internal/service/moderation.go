@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"backend/internal/audit"
+	"backend/internal/models"
+	"backend/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUserBanned is returned when a banned user submits a score
+var ErrUserBanned = errors.New("user is banned")
+
+// SetUserStatus moderates a user: hidden, banned and shadow-banned users leave the
+// public leaderboard, active users rejoin it with their current rating
+// PostgreSQL is written first, together with the audit entry; if Redis then fails the
+// error is returned, and repeating the call brings Redis in line.
+func (s *LeaderboardService) SetUserStatus(ctx context.Context, username, status, reason string) (*models.User, error) {
+	var rows []models.AuditEntry
+	user, err := s.postgresRepo.SetUserStatus(ctx, username, status, reason, func(before, after *models.User) []models.AuditEntry {
+		rows = s.audit.Rows(ctx, audit.Entry{
+			Action: audit.ActionUserModerate,
+			Target: audit.UserTarget(username),
+			Reason: reason,
+			Before: map[string]string{"status": before.Status, "status_reason": before.StatusReason},
+			After:  map[string]string{"status": after.Status, "status_reason": after.StatusReason},
+		})
+		return rows
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Committed(rows)
+
+	// Ratings accepted while hidden may not have reached PostgreSQL yet
+	rating := user.Rating
+	if current, err := s.redisRepo.GetUserScore(ctx, username); err == nil {
+		rating = current
+	}
+
+	if err := s.redisRepo.SetModeration(ctx, username, status, rating); err != nil {
+		s.markDegraded(ctx, err)
+		return nil, fmt.Errorf("user moderated in PostgreSQL but Redis was not updated: %w", err)
+	}
+
+	log.Printf("🛡️ User %s is now %s: %s", username, status, reason)
+	return user, nil
+}
+
+// ListModeratedUsers returns a page of users that are not active, optionally filtered by status
+func (s *LeaderboardService) ListModeratedUsers(ctx context.Context, status string, offset, limit int) (*models.ModeratedUsersResponse, error) {
+	users, total, err := s.postgresRepo.ListModeratedUsers(ctx, status, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list moderated users: %w", err)
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	return &models.ModeratedUsersResponse{Data: users, Offset: offset, Limit: limit, Total: total}, nil
+}
+
+// moderationStatus returns a user's moderation status, from PostgreSQL while degraded
+func (s *LeaderboardService) moderationStatus(ctx context.Context, username string) (string, error) {
+	if !s.degraded.Load() {
+		status, err := s.redisRepo.GetModerationStatus(ctx, username)
+		if err == nil {
+			return status, nil
+		}
+		if !s.markDegraded(ctx, err) {
+			return "", err
+		}
+	}
+
+	user, err := s.postgresRepo.GetUser(ctx, username)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return models.UserActive, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Status, nil
+}
+
+// IsShadowBanned reports whether viewer (a player, or "") is shadow-banned and so gets
+// their own view of the leaderboard. Reads shared between viewers must not be used for them.
+// The status comes from Redis; while degraded every viewer sees the public leaderboard.
+func (s *LeaderboardService) IsShadowBanned(ctx context.Context, viewer string) bool {
+	if viewer == "" || s.degraded.Load() {
+		return false
+	}
+	status, err := s.redisRepo.GetModerationStatus(ctx, viewer)
+	return err == nil && status == models.UserShadowBanned
+}
+
+// GetLeaderboardFor retrieves the leaderboard as seen by viewer (a player, or "")
+// Shadow-banned players still see themselves ranked where their rating would place them.
+// The placement needs Redis; while degraded they see the public leaderboard.
+func (s *LeaderboardService) GetLeaderboardFor(ctx context.Context, viewer string, offset, limit int) (*models.LeaderboardResponse, error) {
+	if !s.IsShadowBanned(ctx, viewer) {
+		return s.GetLeaderboard(ctx, offset, limit)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	page, err := s.shadowLeaderboard(ctx, viewer, offset, limit)
+	if err != nil {
+		log.Printf("⚠️ Failed to place shadow-banned viewer %s: %v", viewer, err)
+		return s.GetLeaderboard(ctx, offset, limit)
+	}
+	return page, nil
+}
+
+// shadowLeaderboard builds a leaderboard page with viewer inserted at their position
+func (s *LeaderboardService) shadowLeaderboard(ctx context.Context, viewer string, offset, limit int) (*models.LeaderboardResponse, error) {
+	rating, err := s.redisRepo.GetUserScore(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	above, err := s.redisRepo.CountAbove(ctx, rating)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.redisRepo.GetTotalUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The viewer sits ahead of the users sharing their rating
+	position := int(above)
+	start, count, insertAt := offset, limit, -1
+	switch {
+	case position < offset:
+		start = offset - 1 // Everyone on the page moves down one
+	case position < offset+limit:
+		count = limit - 1
+		insertAt = position - offset
+	}
+
+	var users []redis.Z
+	if count > 0 {
+		if users, err = s.redisRepo.GetTopUsers(ctx, start, count); err != nil {
+			return nil, err
+		}
+	}
+	if insertAt >= 0 && insertAt <= len(users) {
+		users = append(users[:insertAt], append([]redis.Z{{Member: viewer, Score: float64(rating)}}, users[insertAt:]...)...)
+	}
+
+	entries, err := s.applyTieAwareRanking(ctx, users, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LeaderboardResponse{
+		Data:   entries,
+		Offset: offset,
+		Limit:  limit,
+		Total:  total + 1,
+	}, nil
+}
+
+// SearchUserFor searches for a user as seen by viewer (a player, or "")
+// Shadow-banned players searching for themselves get the rank their rating would earn.
+func (s *LeaderboardService) SearchUserFor(ctx context.Context, viewer, username string) (*models.SearchResponse, error) {
+	if viewer != username || !s.IsShadowBanned(ctx, viewer) {
+		return s.SearchUser(ctx, username)
+	}
+
+	rating, err := s.redisRepo.GetUserScore(ctx, username)
+	if err != nil {
+		return s.SearchUser(ctx, username)
+	}
+	above, err := s.redisRepo.CountAbove(ctx, rating)
+	if err != nil {
+		return s.SearchUser(ctx, username)
+	}
+
+	return &models.SearchResponse{
+		GlobalRank: int(above) + 1,
+		Username:   username,
+		Rating:     rating,
+	}, nil
+}
+
+// GetUserEntriesFor returns the entries of users on the leaderboard as seen by viewer
+// (a player, or ""): a shadow-banned viewer still finds themselves, as with SearchUserFor
+func (s *LeaderboardService) GetUserEntriesFor(ctx context.Context, viewer string, usernames []string) (map[string]models.LeaderboardEntry, error) {
+	entries, err := s.GetUserEntries(ctx, usernames)
+	if err != nil || viewer == "" || !slices.Contains(usernames, viewer) {
+		return entries, err
+	}
+	if _, ok := entries[viewer]; ok {
+		return entries, nil
+	}
+
+	user, err := s.SearchUserFor(ctx, viewer, viewer)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries[viewer] = models.LeaderboardEntry{Rank: user.GlobalRank, Username: viewer, Rating: user.Rating}
+	return entries, nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// redisRecoveryInterval is how often the service probes Redis while degraded
+	redisRecoveryInterval = 2 * time.Second
+
+	// maxReplayQueueSize bounds the number of distinct users buffered for replay
+	maxReplayQueueSize = 100_000
+)
+
+// replayQueue buffers score writes accepted while Redis was unavailable
+// Only the latest rating per user is kept, so replay is a single bulk update.
+// Writes that don't fit are counted instead; PostgreSQL still has them, so recovery then
+// resyncs Redis from PostgreSQL rather than replaying the queue.
+type replayQueue struct {
+	mu      sync.Mutex
+	pending map[string]int
+	dropped int64 // Writes not buffered since the last drain
+}
+
+// newReplayQueue creates an empty replay queue
+func newReplayQueue() *replayQueue {
+	return &replayQueue{
+		pending: make(map[string]int),
+	}
+}
+
+// Add queues a rating for replay, overwriting any older pending rating for the user
+// Returns false if the queue is full and the write could not be buffered
+func (q *replayQueue) Add(username string, rating int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, exists := q.pending[username]; !exists && len(q.pending) >= maxReplayQueueSize {
+		q.dropped++
+		return false
+	}
+	q.pending[username] = rating
+	return true
+}
+
+// Drain removes and returns all pending writes and the number of writes dropped since
+// the last drain
+func (q *replayQueue) Drain() (map[string]int, int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	drained, dropped := q.pending, q.dropped
+	q.pending = make(map[string]int)
+	q.dropped = 0
+	return drained, dropped
+}
+
+// Restore puts back writes that failed to replay, and the dropped count they came with
+// Ratings queued after the drain are newer and take precedence
+func (q *replayQueue) Restore(writes map[string]int, dropped int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.dropped += dropped
+	for username, rating := range writes {
+		if _, exists := q.pending[username]; !exists {
+			q.pending[username] = rating
+		}
+	}
+}
+
+// Pending reports whether writes are waiting to be replayed or were dropped
+func (q *replayQueue) Pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending) > 0 || q.dropped > 0
+}
+
+// queueReplay queues a write made while Redis is unavailable
+func (s *LeaderboardService) queueReplay(username string, rating int) {
+	if !s.replay.Add(username, rating) {
+		log.Printf("⚠️ Replay queue full, Redis will be resynced from PostgreSQL for %s on recovery", username)
+	}
+}
+
+// RunRecovery probes Redis while the service is degraded and replays queued writes
+// once it is reachable again. Blocks until ctx is cancelled.
+func (s *LeaderboardService) RunRecovery(ctx context.Context) {
+	ticker := time.NewTicker(redisRecoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			// Writes can land in the queue just after recovery, so drain even when not degraded
+			if !s.degraded.Load() && !s.replay.Pending() {
+				continue
+			}
+			s.tryRecover(ctx)
+		}
+	}
+}
+
+// tryRecover replays pending writes to Redis and leaves degraded mode on success
+// If the queue overflowed, Redis is resynced from PostgreSQL instead, which also publishes
+// a bulk change so clients refetch
+func (s *LeaderboardService) tryRecover(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := s.redisRepo.Ping(probeCtx); err != nil {
+		return
+	}
+
+	writes, dropped := s.replay.Drain()
+	switch {
+	case dropped > 0:
+		// PostgreSQL holds every queued and dropped write
+		if err := s.SyncRedisFromPostgres(ctx); err != nil {
+			log.Printf("⚠️ Failed to resync Redis after %d dropped writes: %v", dropped, err)
+			s.replay.Restore(writes, dropped)
+			return
+		}
+		log.Printf("✓ Resynced Redis from PostgreSQL after the replay queue dropped %d writes", dropped)
+
+	case len(writes) > 0:
+		if err := s.redisRepo.BulkUpdateScores(ctx, writes); err != nil {
+			log.Printf("⚠️ Failed to replay %d queued writes to Redis: %v", len(writes), err)
+			s.replay.Restore(writes, 0)
+			return
+		}
+		log.Printf("✓ Replayed %d queued writes to Redis", len(writes))
+	}
+
+	if s.degraded.CompareAndSwap(true, false) {
+		log.Println("✅ Redis recovered, leaving degraded mode")
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestReplayQueue(t *testing.T) {
+	q := newReplayQueue()
+	if q.Pending() {
+		t.Fatal("new queue has pending writes")
+	}
+
+	q.Add("alice", 100)
+	q.Add("alice", 120)
+	writes, dropped := q.Drain()
+	if len(writes) != 1 || writes["alice"] != 120 || dropped != 0 {
+		t.Fatalf("drained %v, %d dropped; want only alice's latest rating", writes, dropped)
+	}
+
+	// Ratings queued after a drain win over the restored ones
+	q.Add("alice", 130)
+	q.Restore(map[string]int{"alice": 120, "bob": 90}, 0)
+	writes, _ = q.Drain()
+	if writes["alice"] != 130 || writes["bob"] != 90 {
+		t.Fatalf("after restore got %v, want alice 130 and bob 90", writes)
+	}
+}
+
+func TestReplayQueueOverflow(t *testing.T) {
+	q := newReplayQueue()
+	for i := 0; i < maxReplayQueueSize; i++ {
+		if !q.Add(strconv.Itoa(i), i) {
+			t.Fatalf("write %d rejected before the queue was full", i)
+		}
+	}
+
+	if q.Add("overflow", 1) {
+		t.Fatal("write accepted by a full queue")
+	}
+	// Users already queued are still updated
+	if !q.Add(strconv.Itoa(0), 1) {
+		t.Fatal("update of a queued user rejected")
+	}
+
+	writes, dropped := q.Drain()
+	if len(writes) != maxReplayQueueSize || dropped != 1 {
+		t.Fatalf("drained %d writes, %d dropped; want %d and 1", len(writes), dropped, maxReplayQueueSize)
+	}
+
+	// A failed resync keeps the overflow so the next attempt resyncs again
+	q.Restore(writes, dropped)
+	if _, dropped := q.Drain(); dropped != 1 {
+		t.Fatalf("dropped %d after restore, want 1", dropped)
+	}
+	if q.Pending() {
+		t.Fatal("queue still pending after drain")
+	}
+}
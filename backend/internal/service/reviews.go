@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"backend/internal/anticheat"
+	"backend/internal/audit"
+	"backend/internal/models"
+)
+
+// WithAntiCheat checks submissions made through SubmitScore against the engine's rules
+func (s *LeaderboardService) WithAntiCheat(engine *anticheat.Engine) *LeaderboardService {
+	s.antiCheat = engine
+	return s
+}
+
+// AntiCheat returns the anti-cheat engine (nil when submissions are not checked)
+func (s *LeaderboardService) AntiCheat() *anticheat.Engine {
+	return s.antiCheat
+}
+
+// SubmitScore applies a score submitted by a client, after the anti-cheat rules
+// Flagged updates are applied and queued for review; blocked updates are only queued,
+// and the returned review has the block action. Banned users get ErrUserBanned.
+// Internal writes (simulator, admin operations) use UpdateScore and are not checked.
+func (s *LeaderboardService) SubmitScore(ctx context.Context, username string, rating int, apiKeyID uint) (*models.ScoreReview, error) {
+	status, err := s.moderationStatus(ctx, username)
+	if err != nil {
+		log.Printf("⚠️ Moderation check skipped for %s: %v", username, err)
+	}
+	if status == models.UserBanned {
+		return nil, ErrUserBanned
+	}
+
+	var previous *int
+	if ratings, err := s.GetUserRatings(ctx, []string{username}); err == nil {
+		if current, ok := ratings[username]; ok {
+			previous = &current
+		}
+	}
+
+	verdict, err := s.antiCheat.Evaluate(ctx, username, previous, rating)
+	if err != nil {
+		log.Printf("⚠️ Anti-cheat check skipped for %s: %v", username, err)
+	}
+
+	var review *models.ScoreReview
+	if verdict.Action != "" {
+		review = &models.ScoreReview{
+			Username:       username,
+			PreviousRating: previous,
+			Rating:         rating,
+			Action:         verdict.Action,
+			Reasons:        verdict.Reasons(),
+			Status:         models.ReviewPending,
+		}
+		if apiKeyID != 0 {
+			review.APIKeyID = &apiKeyID
+		}
+		if err := s.postgresRepo.CreateReview(ctx, review); err != nil {
+			if verdict.Action == models.ReviewActionBlock {
+				return nil, fmt.Errorf("failed to queue blocked update for review: %w", err)
+			}
+			log.Printf("⚠️ Failed to queue flagged update of %s for review: %v", username, err)
+		}
+		log.Printf("🚩 Anti-cheat %s for %s (%d): %s", verdict.Action, username, rating, review.Reasons)
+
+		if verdict.Action == models.ReviewActionBlock {
+			return review, nil
+		}
+	}
+
+	return review, s.UpdateScore(ctx, username, rating, apiKeyID)
+}
+
+// ListReviews returns a page of the review queue, optionally filtered by status
+func (s *LeaderboardService) ListReviews(ctx context.Context, status string, offset, limit int) (*models.ReviewListResponse, error) {
+	reviews, total, err := s.postgresRepo.ListReviews(ctx, status, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	if reviews == nil {
+		reviews = []models.ScoreReview{}
+	}
+	return &models.ReviewListResponse{Data: reviews, Offset: offset, Limit: limit, Total: total}, nil
+}
+
+// ResolveReview approves or rejects a pending review
+// Approving a blocked update applies it. Rejecting a flagged update, which was already
+// applied, restores the previous rating unless the user has been updated since.
+// If applying the resolution fails the review is reopened, so it can be resolved again.
+// reviewerKeyID is the admin key resolving the review; resulting writes are attributed to it.
+func (s *LeaderboardService) ResolveReview(ctx context.Context, id uint, approve bool, reviewerKeyID uint, note string) (_ *models.ScoreReview, err error) {
+	status := models.ReviewRejected
+	if approve {
+		status = models.ReviewApproved
+	}
+
+	// Claim the review first, so two reviewers cannot both act on it
+	review, err := s.postgresRepo.ResolveReview(ctx, id, status, reviewerKeyID, note)
+	if err != nil {
+		return nil, err
+	}
+	action := audit.ActionReviewReject
+	if approve {
+		action = audit.ActionReviewApprove
+	}
+	defer func() {
+		// Recorded once the outcome is known, including whether the update was reverted.
+		// A failed attempt is recorded too, with the review reopened for another try.
+		var after interface{} = review
+		if err != nil {
+			if reopenErr := s.postgresRepo.ReopenReview(ctx, id, status); reopenErr != nil {
+				log.Printf("⚠️ Failed to reopen review %d after %v: %v", id, err, reopenErr)
+			} else {
+				review.Status = models.ReviewPending
+			}
+			after = map[string]interface{}{"review": review, "error": err.Error()}
+		}
+		s.audit.Record(ctx, audit.Entry{
+			Action: action,
+			Target: audit.ReviewTarget(id),
+			Reason: note,
+			Before: map[string]string{"status": models.ReviewPending},
+			After:  after,
+		})
+	}()
+
+	switch {
+	case approve && review.Action == models.ReviewActionBlock:
+		if err := s.UpdateScore(ctx, review.Username, review.Rating, reviewerKeyID); err != nil {
+			return nil, fmt.Errorf("review approved but the update failed: %w", err)
+		}
+		// Now applied, so it counts towards the user's later anti-cheat checks
+		if err := s.antiCheat.Record(ctx, review.Username, review.PreviousRating, review.Rating); err != nil {
+			log.Printf("⚠️ Failed to record approved update of %s for anti-cheat: %v", review.Username, err)
+		}
+	case !approve && review.Action == models.ReviewActionFlag && review.PreviousRating != nil:
+		ratings, err := s.GetUserRatings(ctx, []string{review.Username})
+		if err != nil {
+			return nil, fmt.Errorf("review rejected but the current rating is unavailable: %w", err)
+		}
+		if ratings[review.Username] != review.Rating {
+			break // Updated since; leave the newer rating alone
+		}
+		if err := s.UpdateScore(ctx, review.Username, *review.PreviousRating, reviewerKeyID); err != nil {
+			return nil, fmt.Errorf("review rejected but the revert failed: %w", err)
+		}
+		if err := s.postgresRepo.MarkReviewReverted(ctx, id); err != nil {
+			log.Printf("⚠️ Failed to mark review %d as reverted: %v", id, err)
+		}
+		review.Reverted = true
+	}
+
+	return review, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/audit"
+	"backend/internal/models"
+)
+
+var (
+	// ErrRollbackUnfiltered is returned for a rollback that names neither users nor a start time
+	ErrRollbackUnfiltered = errors.New("rollback needs usernames or a from time")
+
+	// ErrRollbackUnavailable is returned while degraded, since removed users could not be
+	// taken off the Redis leaderboard
+	ErrRollbackUnavailable = errors.New("rollbacks are unavailable while Redis is down")
+)
+
+// SetRating overrides a user's rating on behalf of an admin, recording reason in the
+// score history
+// PostgreSQL is written synchronously, together with the audit entry, so the override is
+// recorded before it is visible.
+func (s *LeaderboardService) SetRating(ctx context.Context, username string, rating int, adminKeyID uint, reason string) (*models.ScoreEvent, error) {
+	var rows []models.AuditEntry
+	event, err := s.postgresRepo.SetRating(ctx, username, rating, adminKeyID, reason, func(event *models.ScoreEvent) []models.AuditEntry {
+		rows = s.audit.Rows(ctx, audit.Entry{
+			Action: audit.ActionUserSetRating,
+			Target: audit.UserTarget(username),
+			Reason: reason,
+			Before: map[string]int{"rating": *event.PreviousRating},
+			After:  map[string]int{"rating": rating},
+		})
+		return rows
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Committed(rows)
+
+	// Published as a normal change, so clients update incrementally
+	if s.degraded.Load() {
+		s.queueReplay(username, rating)
+	} else if err := s.redisRepo.UpdateScore(ctx, username, rating); err != nil {
+		if !s.markDegraded(ctx, err) {
+			return nil, fmt.Errorf("rating set in PostgreSQL but Redis was not updated: %w", err)
+		}
+		s.queueReplay(username, rating)
+	}
+
+	log.Printf("✏️ Rating of %s set to %d (was %d): %s", username, rating, *event.PreviousRating, reason)
+	return event, nil
+}
+
+// Rollback reverts the score updates selected by req on behalf of an admin
+// Each matched user goes back to their rating before their first matched update; users
+// created by a matched update are removed. Submissions still queued for PostgreSQL when
+// the rollback runs can land after it, so ban exploiting users first.
+func (s *LeaderboardService) Rollback(ctx context.Context, req models.RollbackRequest, adminKeyID uint) (*models.RollbackResponse, error) {
+	if len(req.Usernames) == 0 && req.From == nil {
+		return nil, ErrRollbackUnfiltered
+	}
+	if !req.DryRun && s.degraded.Load() {
+		return nil, ErrRollbackUnavailable
+	}
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+
+	var rows []models.AuditEntry
+	changes, err := s.postgresRepo.RollbackScores(ctx, req.Usernames, req.From, to, adminKeyID, req.Reason, req.DryRun,
+		func(changes []models.RollbackChange) []models.AuditEntry {
+			rows = s.rollbackAuditRows(ctx, req, to, changes)
+			return rows
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back scores: %w", err)
+	}
+	s.audit.Committed(rows)
+
+	response := &models.RollbackResponse{DryRun: req.DryRun, Changes: changes}
+	if response.Changes == nil {
+		response.Changes = []models.RollbackChange{}
+	}
+	ratings := make(map[string]int, len(changes))
+	var removed []string
+	for _, change := range changes {
+		if change.To == nil {
+			removed = append(removed, change.Username)
+		} else {
+			ratings[change.Username] = *change.To
+		}
+	}
+	response.Restored = len(ratings)
+	response.Removed = len(removed)
+
+	if req.DryRun || len(changes) == 0 {
+		return response, nil
+	}
+
+	if err := s.applyRollbackToRedis(ctx, ratings, removed); err != nil {
+		return nil, fmt.Errorf("scores rolled back in PostgreSQL but Redis was not updated: %w", err)
+	}
+
+	log.Printf("⏪ Rolled back %d users (%d restored, %d removed): %s",
+		len(changes), response.Restored, response.Removed, req.Reason)
+	return response, nil
+}
+
+// rollbackAuditRows builds the audit rows of a rollback, with one entry for the rollback
+// and one per deleted user
+func (s *LeaderboardService) rollbackAuditRows(ctx context.Context, req models.RollbackRequest, to time.Time, changes []models.RollbackChange) []models.AuditEntry {
+	before := make(map[string]int, len(changes))
+	after := make(map[string]*int, len(changes))
+	entries := []audit.Entry{{
+		Action: audit.ActionScoresRollback,
+		Reason: req.Reason,
+		Before: before,
+		After: map[string]interface{}{
+			"usernames": req.Usernames,
+			"from":      req.From,
+			"to":        to,
+			"ratings":   after, // null for deleted users
+		},
+	}}
+	for _, change := range changes {
+		before[change.Username] = change.From
+		after[change.Username] = change.To
+		if change.To == nil {
+			entries = append(entries, audit.Entry{
+				Action: audit.ActionUserDelete,
+				Target: audit.UserTarget(change.Username),
+				Reason: req.Reason,
+				Before: map[string]int{"rating": change.From},
+			})
+		}
+	}
+	return s.audit.Rows(ctx, entries...)
+}
+
+// applyRollbackToRedis applies a rollback committed to PostgreSQL to Redis
+// If Redis fails, restored ratings are queued for replay but removed users stay on the
+// Redis leaderboard, so the error is returned for the admin to act on.
+func (s *LeaderboardService) applyRollbackToRedis(ctx context.Context, ratings map[string]int, removed []string) error {
+	err := s.redisRepo.ApplyRollback(ctx, ratings, removed)
+	if err == nil {
+		return nil
+	}
+	if s.markDegraded(ctx, err) {
+		for username, rating := range ratings {
+			s.queueReplay(username, rating)
+		}
+	}
+	return err
+}
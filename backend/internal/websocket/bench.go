@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Transport of the in-memory clients created by RunFanoutBenchmark
+const transportSimulated = "simulated"
+
+// BenchmarkConfig configures an in-process fan-out benchmark
+type BenchmarkConfig struct {
+	Clients    int           // Simulated connections
+	Shards     int           // Hub shards (0 = one per CPU)
+	Broadcasts int           // Version broadcasts to time
+	Timeout    time.Duration // Maximum time for a single broadcast to reach every client
+}
+
+// BenchmarkResult holds the outcome of RunFanoutBenchmark
+type BenchmarkResult struct {
+	Clients   int
+	Shards    int
+	Setup     time.Duration   // Time to register every client
+	Latencies []time.Duration // Per broadcast, time until every client received it
+	Delivered int64
+	Dropped   int64
+	Evictions int64
+}
+
+// RunFanoutBenchmark registers simulated clients with a hub and times version broadcasts
+// Each simulated client is a goroutine draining its send channel, so the measurement
+// covers the hub's fan-out and channel delivery without network I/O. No Redis is needed.
+func RunFanoutBenchmark(ctx context.Context, cfg BenchmarkConfig) (*BenchmarkResult, error) {
+	if cfg.Clients <= 0 || cfg.Broadcasts <= 0 {
+		return nil, fmt.Errorf("clients and broadcasts must be positive")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	hub := NewHub(nil, nil).WithShards(cfg.Shards)
+	for _, shard := range hub.shards {
+		go shard.run(ctx)
+	}
+
+	// Every client counts the frames it receives; the one completing a broadcast signals it
+	var received, target atomic.Int64
+	target.Store(-1)
+	broadcastDone := make(chan struct{}, 1)
+
+	start := time.Now()
+	clients := make([]*Client, cfg.Clients)
+	for i := range clients {
+		client := newClient(hub, transportSimulated, ConnectOptions{})
+		clients[i] = client
+
+		// Registered directly, bypassing register's per-client logging
+		hub.shardFor(client).add(client)
+		hub.clientCount.Add(1)
+
+		go func() {
+			for message := range client.send {
+				client.markDelivered(message)
+				if received.Add(1) == target.Load() {
+					broadcastDone <- struct{}{}
+				}
+			}
+		}()
+	}
+	result := &BenchmarkResult{
+		Clients: cfg.Clients,
+		Shards:  len(hub.shards),
+		Setup:   time.Since(start),
+	}
+
+	defer func() {
+		for _, client := range clients {
+			hub.shardFor(client).remove(client)
+		}
+	}()
+
+	for version := int64(1); version <= int64(cfg.Broadcasts); version++ {
+		target.Store(version * int64(cfg.Clients))
+
+		start := time.Now()
+		hub.broadcastVersion(version)
+
+		select {
+		case <-broadcastDone:
+			result.Latencies = append(result.Latencies, time.Since(start))
+		case <-time.After(cfg.Timeout):
+			return nil, fmt.Errorf("broadcast %d reached %d of %d clients within %s",
+				version, received.Load()-(version-1)*int64(cfg.Clients), cfg.Clients, cfg.Timeout)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	result.Delivered = received.Load()
+	result.Dropped = hub.metrics.dropped.Load()
+	result.Evictions = hub.metrics.evictions.Load()
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"log"
+	"sort"
+)
+
+const (
+	// Consecutive dropped messages after which a client is disconnected as a slow consumer
+	// Broadcasts are coalesced, so a full 256-message buffer several times in a row means
+	// the client is not reading at all
+	maxConsecutiveDrops = 5
+
+	// Number of most-lagging clients listed in metrics
+	lagReportSize = 10
+)
+
+// ClientLag describes how far a client is behind the hub
+type ClientLag struct {
+	ID         uint64 `json:"id"`
+	Transport  string `json:"transport"`
+	Queued     int    `json:"queued"`      // Messages waiting in the send buffer
+	VersionLag int64  `json:"version_lag"` // Hub version minus the last version written to the peer
+	Drops      int32  `json:"drops"`       // Consecutive messages dropped
+}
+
+// LagReport summarises client lag across the hub
+type LagReport struct {
+	MaxVersionLag int64       `json:"max_version_lag"`
+	MaxQueued     int         `json:"max_queued"`
+	Lagging       int         `json:"lagging"` // Clients with undelivered versions or queued messages
+	Slowest       []ClientLag `json:"slowest"`
+}
+
+// accepted records a message queued for the client
+func (c *Client) accepted() {
+	c.hub.metrics.sent.Add(1)
+	c.drops.Store(0)
+}
+
+// dropped records a message the client's full buffer couldn't take,
+// evicting the client once it is persistently slow
+func (c *Client) dropped() {
+	c.hub.metrics.dropped.Add(1)
+	if c.drops.Add(1) >= maxConsecutiveDrops {
+		c.evict("send buffer full")
+	}
+}
+
+// evict disconnects a slow client; its write loop closes the connection and unregisters it
+func (c *Client) evict(reason string) {
+	c.evictOnce.Do(func() {
+		c.hub.metrics.evictions.Add(1)
+		log.Printf("🐢 Evicting slow %s client %d: %s", c.transport, c.id, reason)
+		close(c.evicted)
+	})
+}
+
+// markDelivered records the version of a message written to the peer
+func (c *Client) markDelivered(message *frame) {
+	for {
+		delivered := c.deliveredVersion.Load()
+		if message.version <= delivered || c.deliveredVersion.CompareAndSwap(delivered, message.version) {
+			return
+		}
+	}
+}
+
+// lag reports how far the client is behind the given hub version
+func (c *Client) lag(version int64) ClientLag {
+	lag := ClientLag{
+		ID:        c.id,
+		Transport: c.transport,
+		Queued:    len(c.send),
+		Drops:     c.drops.Load(),
+	}
+	// Clients that haven't been written a version yet are still in their handshake
+	if delivered := c.deliveredVersion.Load(); delivered > 0 && version > delivered {
+		lag.VersionLag = version - delivered
+	}
+	return lag
+}
+
+// lagReport collects per-client lag, listing the most-lagging clients
+func (h *Hub) lagReport() LagReport {
+	version := h.lastVersion.Load()
+
+	lags := make([]ClientLag, 0, h.GetClientCount())
+	for _, shard := range h.shards {
+		shard.each(func(client *Client) {
+			lags = append(lags, client.lag(version))
+		})
+	}
+
+	report := LagReport{Slowest: []ClientLag{}}
+	for _, lag := range lags {
+		if lag.VersionLag > report.MaxVersionLag {
+			report.MaxVersionLag = lag.VersionLag
+		}
+		if lag.Queued > report.MaxQueued {
+			report.MaxQueued = lag.Queued
+		}
+		if lag.VersionLag > 0 || lag.Queued > 0 {
+			report.Lagging++
+			report.Slowest = append(report.Slowest, lag)
+		}
+	}
+
+	sort.Slice(report.Slowest, func(i, j int) bool {
+		a, b := report.Slowest[i], report.Slowest[j]
+		if a.VersionLag != b.VersionLag {
+			return a.VersionLag > b.VersionLag
+		}
+		return a.Queued > b.Queued
+	})
+	if len(report.Slowest) > lagReportSize {
+		report.Slowest = report.Slowest[:lagReportSize]
+	}
+	return report
+}
@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encodings a WebSocket client can negotiate through the Sec-WebSocket-Protocol header
+// Clients that request no subprotocol get JSON. SSE is always JSON.
+const (
+	EncodingJSON    = "json"
+	EncodingMsgPack = "msgpack"
+)
+
+// Subprotocols lists the WebSocket subprotocols the hub accepts, in order of preference
+var Subprotocols = []string{EncodingMsgPack, EncodingJSON}
+
+// encodingForSubprotocol maps the negotiated subprotocol to a message encoding
+func encodingForSubprotocol(subprotocol string) string {
+	if subprotocol == EncodingMsgPack {
+		return EncodingMsgPack
+	}
+	return EncodingJSON
+}
+
+// payload returns the frame encoded for a client's encoding
+// The MessagePack form is encoded the first time a MessagePack client needs it and
+// shared by every other MessagePack client, so each event is encoded once per encoding
+func (f *frame) payload(encoding string) []byte {
+	if encoding != EncodingMsgPack {
+		return f.data
+	}
+	f.msgpackOnce.Do(func() {
+		f.msgpack = encodeMsgPack(f.message)
+	})
+	return f.msgpack
+}
+
+// encodeMsgPack encodes a hub message to MessagePack, using the JSON field names
+// so both encodings carry the same keys
+func encodeMsgPack(v interface{}) []byte {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		log.Printf("❌ Failed to encode hub message as MessagePack: %v", err)
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// decodeClientMessage decodes a client request sent as JSON text or as MessagePack binary
+func decodeClientMessage(binary bool, data []byte) (ClientMessage, error) {
+	var msg ClientMessage
+	if !binary {
+		err := json.Unmarshal(data, &msg)
+		return msg, err
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	err := dec.Decode(&msg)
+	return msg, err
+}
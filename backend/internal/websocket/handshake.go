@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ConnectOptions are the handshake parameters a client presents when connecting
+//
+//	/ws?version=12340        resume from the last version seen
+//	/ws?snapshot=50          push ranks 1-50 as the first message
+//	/ws?range=101-150        push ranks 101-150 first and subscribe to that range
+type ConnectOptions struct {
+	// LastVersion is the last version seen before reconnecting (0 starts a fresh session)
+	LastVersion int64
+
+	// SnapshotStart and SnapshotEnd select the ranks pushed as a SNAPSHOT (0 = no snapshot)
+	SnapshotStart int
+	SnapshotEnd   int
+
+	// SubscribeSnapshot also subscribes the client to the snapshot range
+	SubscribeSnapshot bool
+
+	// Player is the username authenticated by a player token ("" for anonymous clients)
+	// Set by the caller after authentication, never from query parameters
+	Player string
+}
+
+// ParseConnectOptions reads ConnectOptions from handshake query parameters
+// Invalid values are ignored so a bad parameter never prevents connecting
+func ParseConnectOptions(query func(key string) string) ConnectOptions {
+	var opts ConnectOptions
+
+	if version, err := strconv.ParseInt(query("version"), 10, 64); err == nil && version > 0 {
+		opts.LastVersion = version
+	}
+
+	if n, err := strconv.Atoi(query("snapshot")); err == nil && n >= 1 && n <= maxRangeSize {
+		opts.SnapshotStart, opts.SnapshotEnd = 1, n
+	}
+
+	if startStr, endStr, ok := strings.Cut(query("range"), "-"); ok {
+		start, startErr := strconv.Atoi(startStr)
+		end, endErr := strconv.Atoi(endStr)
+		if startErr == nil && endErr == nil && start >= 1 && end >= start && end-start+1 <= maxRangeSize {
+			opts.SnapshotStart, opts.SnapshotEnd = start, end
+			opts.SubscribeSnapshot = true
+		}
+	}
+
+	return opts
+}
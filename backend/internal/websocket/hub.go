@@ -5,56 +5,161 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"backend/internal/models"
 	"backend/internal/repository"
 
 	"github.com/gofiber/websocket/v2"
-	"github.com/redis/go-redis/v9"
 )
 
 const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
 
-	// Heartbeat interval for version updates (prevents request storm)
-	// Frontend only fetches when version changes, max once per heartbeat
-	versionHeartbeatInterval = 2 * time.Second
+	// Coalescing window for change notifications (prevents request storm)
+	// Bursts of writes within the window produce a single VERSION_UPDATE
+	broadcastCoalesceWindow = 50 * time.Millisecond
 
-	// Maximum message size allowed from peer
+	// Interval for reconciling against VersionKey in case a Pub/Sub message was lost
+	versionReconcileInterval = 10 * time.Second
+
+	// Buffer for change notifications between the Pub/Sub listener and the hub loop
+	changeBufferSize = 1024
+
+	// Time allowed to read the next pong message from the peer
+	pongWait = 60 * time.Second
+
+	// Send pings to peer with this period (must be less than pongWait)
+	pingPeriod = (pongWait * 9) / 10
+
+	// Maximum message size allowed from peer (subscription requests are well below this)
 	maxMessageSize = 512
+
+	// Maximum change feed pages replayed to a resuming client before asking it to resync
+	maxResumePages = 5
+
+	// Buffered messages per client
+	clientSendBuffer = 256
 )
 
-// Client represents a WebSocket client connection
+// Transports a client can be connected over
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+)
+
+// Client represents a WebSocket or Server-Sent Events client connection
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	id        uint64
+	hub       *Hub
+	conn      *websocket.Conn // nil for SSE clients
+	transport string
+	encoding  string // EncodingJSON or EncodingMsgPack
+	send      chan *frame
+	opts      ConnectOptions
+
+	// Consecutive messages dropped because the send buffer was full
+	drops atomic.Int32
+
+	// Highest leaderboard version written to the peer, used to report lag
+	deliveredVersion atomic.Int64
+
+	// Closed when the client is evicted as a slow consumer
+	evicted   chan struct{}
+	evictOnce sync.Once
+
+	// Version sent during the handshake, checked again once the client is registered
+	initialVersion int64
+
+	// Entries of the handshake snapshot, adopted by the snapshot range subscription,
+	// and the viewer they were read for
+	snapshotEntries []models.LeaderboardEntry
+	snapshotViewer  string
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
 type Hub struct {
-	// Registered clients
-	clients map[*Client]bool
+	// Registered clients, split into shards that each fan out broadcasts on their own goroutine
+	shards []*hubShard
 
-	// Register requests from clients
-	register chan *Client
-
-	// Unregister requests from clients
-	unregister chan *Client
+	// Number of registered clients across all shards
+	clientCount atomic.Int64
 
 	// Redis repository for fetching leaderboard data
 	redisRepo *repository.RedisRepository
 
-	// Redis client for pub/sub (deprecated - using version polling instead)
-	redisClient *redis.Client
+	// Leaderboard reads for subscriptions and session resume
+	reader LeaderboardReader
+
+	// Change notifications received from Redis Pub/Sub
+	changes chan models.ChangeEvent
 
-	// Mutex for thread-safe operations
-	mu sync.RWMutex
-	
 	// Last known version for change detection
-	lastVersion int64
+	lastVersion atomic.Int64
+
+	// Closed and replaced whenever lastVersion changes, waking long-poll waiters
+	waitMu      sync.Mutex
+	versionWait chan struct{}
+
+	// Range, user and "around me" subscriptions
+	subs *subscriptionManager
+
+	// Per-user rank watches
+	watches *watchManager
+
+	// Viewer counts aggregated across instances
+	presence *presenceTracker
+
+	// Handshake snapshots, encoded once per page and version
+	snapshots *snapshotCache
+
+	// Delivery counters shared by every transport
+	metrics hubMetrics
+
+	// Source of client ids
+	nextClientID atomic.Uint64
+}
+
+// hubMetrics tracks message delivery across all clients
+type hubMetrics struct {
+	broadcasts atomic.Int64
+	sent       atomic.Int64
+	dropped    atomic.Int64
+	evictions  atomic.Int64 // Clients disconnected as slow consumers
+	waiters    atomic.Int64 // Long-poll requests currently blocked in WaitForVersion
+}
+
+// frame is an encoded hub message, shared by every client it is sent to
+type frame struct {
+	event   string      // Message type, also the SSE event name
+	version int64       // Leaderboard version the message refers to, also the SSE event id (0 = none)
+	message interface{} // Source message, kept for the other encodings
+	data    []byte      // JSON encoding
+
+	// MessagePack encoding, built on first use (see payload)
+	msgpackOnce sync.Once
+	msgpack     []byte
+}
+
+// newFrame encodes a hub message once for all of its recipients
+func newFrame(event string, version int64, message interface{}) *frame {
+	data := encodeMessage(message)
+	if data == nil {
+		return nil
+	}
+	return &frame{event: event, version: version, message: message, data: data}
+}
+
+// versionFrame builds a VERSION_UPDATE or RESYNC message
+func versionFrame(event string, version int64) *frame {
+	return newFrame(event, version, VersionUpdate{Type: event, Version: version})
+}
+
+// controlFrame builds an acknowledgement or ERROR message
+func controlFrame(event, subscription, message string) *frame {
+	return newFrame(event, 0, ControlMessage{Type: event, Subscription: subscription, Message: message})
 }
 
 // VersionUpdate represents the version heartbeat message
@@ -63,55 +168,85 @@ type VersionUpdate struct {
 	Version int64  `json:"version"`
 }
 
-// LeaderboardUpdate represents the data structure sent to WebSocket clients (deprecated)
-type LeaderboardUpdate struct {
-	Type      string                     `json:"type"`
-	Timestamp string                     `json:"timestamp"`
-	Data      []models.LeaderboardEntry  `json:"data"`
-	Total     int                        `json:"total"`
+// DeltaMessage replays the changes a resuming client missed
+type DeltaMessage struct {
+	Type    string               `json:"type"`
+	Since   int64                `json:"since"`
+	Version int64                `json:"version"`
+	Changes []models.ChangeEntry `json:"changes"`
 }
 
 // NewHub creates a new WebSocket hub
-func NewHub(redisRepo *repository.RedisRepository, redisClient *redis.Client) *Hub {
-	return &Hub{
-		register:    make(chan *Client),
-		unregister:  make(chan *Client),
-		clients:     make(map[*Client]bool),
+// reader resolves client subscriptions (normally the LeaderboardService)
+func NewHub(redisRepo *repository.RedisRepository, reader LeaderboardReader) *Hub {
+	h := &Hub{
+		shards:      newShards(0),
 		redisRepo:   redisRepo,
-		redisClient: redisClient,
-		lastVersion: 0,
+		reader:      reader,
+		changes:     make(chan models.ChangeEvent, changeBufferSize),
+		versionWait: make(chan struct{}),
 	}
+	h.subs = newSubscriptionManager(h, reader)
+	h.watches = newWatchManager(h, reader)
+	h.presence = newPresenceTracker(h)
+	h.snapshots = newSnapshotCache(reader)
+	return h
+}
+
+// WithShards sets the number of client shards (0 = one per CPU)
+// Must be called before Run and before any client connects
+func (h *Hub) WithShards(n int) *Hub {
+	h.shards = newShards(n)
+	return h
 }
 
 // Run starts the WebSocket hub
 func (h *Hub) Run(ctx context.Context) {
 	log.Println("🚀 WebSocket Hub started")
 
-	// Ticker to check version every 2 seconds
-	versionTicker := time.NewTicker(versionHeartbeatInterval)
-	defer versionTicker.Stop()
+	// Receive change notifications from every instance via Redis Pub/Sub
+	go h.listenForChanges(ctx)
+
+	// Resolve and push subscriptions off the hub loop
+	go h.subs.run(ctx)
+	go h.watches.run(ctx)
+
+	// Heartbeat viewer counts to Redis and push changes
+	go h.presence.run(ctx)
+
+	// Each shard fans out broadcasts to its own clients
+	for _, shard := range h.shards {
+		go shard.run(ctx)
+	}
+	log.Printf("🧩 WebSocket Hub fanning out over %d shards", len(h.shards))
+
+	// Load the current version so long-poll waiters don't wait for the first change
+	h.checkAndBroadcastVersion(ctx)
+
+	// Safety net in case a notification is lost while Pub/Sub reconnects
+	reconcileTicker := time.NewTicker(versionReconcileInterval)
+	defer reconcileTicker.Stop()
+
+	// Coalescing timer, armed by the first notification after a broadcast
+	var flush <-chan time.Time
+	var pendingVersion int64
 
 	for {
 		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
-			log.Printf("✅ Client connected (Total: %d)", len(h.clients))
-
-			// Send initial version to new client
-			h.sendInitialVersion(client)
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+		case event := <-h.changes:
+			if event.Version > pendingVersion {
+				pendingVersion = event.Version
+			}
+			if flush == nil {
+				flush = time.After(broadcastCoalesceWindow)
 			}
-			h.mu.Unlock()
-			log.Printf("❌ Client disconnected (Total: %d)", len(h.clients))
 
-		case <-versionTicker.C:
+		case <-flush:
+			flush = nil
+			h.broadcastVersion(pendingVersion)
+			pendingVersion = 0
+
+		case <-reconcileTicker.C:
 			// Check if version changed and broadcast if necessary
 			h.checkAndBroadcastVersion(ctx)
 
@@ -122,7 +257,38 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
-// checkAndBroadcastVersion checks if the version has changed and broadcasts to all clients
+// listenForChanges forwards ChangeEvents from Redis Pub/Sub to the hub loop
+func (h *Hub) listenForChanges(ctx context.Context) {
+	pubsub := h.redisRepo.SubscribeChanges(ctx)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var event models.ChangeEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("⚠️ Ignoring malformed change event: %v", err)
+				continue
+			}
+
+			select {
+			case h.changes <- event:
+			default:
+				// Hub is behind; a later event or the reconcile tick carries a newer version
+			}
+		}
+	}
+}
+
+// checkAndBroadcastVersion reads the version from Redis and broadcasts it if it changed
 func (h *Hub) checkAndBroadcastVersion(ctx context.Context) {
 	currentVersion, err := h.redisRepo.GetLeaderboardVersion(ctx)
 	if err != nil {
@@ -130,105 +296,330 @@ func (h *Hub) checkAndBroadcastVersion(ctx context.Context) {
 		return
 	}
 
+	// Version went backwards (Redis was flushed or reseeded), start tracking afresh
+	if currentVersion < h.lastVersion.Load() {
+		h.lastVersion.Store(0)
+	}
+
+	h.broadcastVersion(currentVersion)
+}
+
+// broadcastVersion sends a VERSION_UPDATE to all clients if the version moved forward
+func (h *Hub) broadcastVersion(currentVersion int64) {
 	// Only broadcast if version has changed
-	if currentVersion != h.lastVersion {
-		h.lastVersion = currentVersion
+	if currentVersion > h.lastVersion.Load() {
+		h.lastVersion.Store(currentVersion)
+		h.wakeWaiters()
 		log.Printf("📡 Version changed to %d, broadcasting to clients", currentVersion)
 
-		// Create version update message
-		update := VersionUpdate{
-			Type:    "VERSION_UPDATE",
-			Version: currentVersion,
+		// Create version update message, encoded once for every client
+		message := versionFrame("VERSION_UPDATE", currentVersion)
+		if message == nil {
+			return
+		}
+		h.metrics.broadcasts.Add(1)
+
+		// Broadcast to all connected clients; each shard fans out on its own goroutine
+		for _, shard := range h.shards {
+			shard.publish(message)
 		}
 
-		// Marshal to JSON
-		message, err := json.Marshal(update)
+		// Re-resolve subscriptions against the new version
+		h.subs.notify(currentVersion)
+		h.watches.notify(currentVersion)
+	}
+}
+
+// trySend queues a message for a single client without blocking
+// Returns false if the client has disconnected or its buffer is full
+func (h *Hub) trySend(client *Client, message *frame) bool {
+	if message == nil {
+		return false
+	}
+
+	shard := h.shardFor(client)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if _, ok := shard.clients[client]; !ok {
+		return false
+	}
+
+	select {
+	case client.send <- message:
+		client.accepted()
+		return true
+	default:
+		client.dropped()
+		return false
+	}
+}
+
+// handleClientMessage processes a subscription or watch request from a client
+func (h *Hub) handleClientMessage(client *Client, binary bool, data []byte) {
+	msg, err := decodeClientMessage(binary, data)
+	if err != nil {
+		h.trySend(client, controlFrame("ERROR", "", "invalid message: "+err.Error()))
+		return
+	}
+
+	switch msg.Action {
+	case "subscribe":
+		spec, err := parseSubscription(msg)
 		if err != nil {
-			log.Printf("❌ Failed to marshal version update: %v", err)
+			h.trySend(client, controlFrame("ERROR", "", err.Error()))
 			return
 		}
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		spec = spec.forViewer(h.viewer(ctx, client))
+		cancel()
+		if err := h.subs.subscribe(client, spec, h.lastVersion.Load(), nil); err != nil {
+			h.trySend(client, controlFrame("ERROR", spec.key(), err.Error()))
+		}
 
-		// Broadcast to all connected clients
-		h.mu.RLock()
-		for client := range h.clients {
-			select {
-			case client.send <- message:
-			default:
-				// Client's send buffer is full, skip this client
-				log.Printf("⚠️ Client send buffer full, skipping")
-			}
+	case "unsubscribe":
+		if !h.subs.unsubscribe(client, msg.Subscription) {
+			h.trySend(client, controlFrame("ERROR", msg.Subscription, "not subscribed"))
+			return
+		}
+		h.trySend(client, controlFrame("UNSUBSCRIBED", msg.Subscription, ""))
+
+	case "watch":
+		// Authenticated players watch themselves unless they name someone else
+		if msg.Username == "" {
+			msg.Username = client.opts.Player
+		}
+		if err := h.watches.watch(client, msg.Username, msg.TopN); err != nil {
+			h.trySend(client, controlFrame("ERROR", watchKey(msg.Username), err.Error()))
 		}
-		h.mu.RUnlock()
+
+	case "unwatch":
+		if !h.watches.unwatch(client, msg.Username) {
+			h.trySend(client, controlFrame("ERROR", watchKey(msg.Username), "not watching"))
+			return
+		}
+		h.trySend(client, controlFrame("UNWATCHED", watchKey(msg.Username), ""))
+
+	default:
+		h.trySend(client, controlFrame("ERROR", "", "unknown action "+msg.Action))
 	}
 }
 
-// sendInitialVersion sends the current version to a newly connected client
-func (h *Hub) sendInitialVersion(client *Client) {
-	ctx := context.Background()
+// sendInitialState brings a newly connected client up to date before it is registered,
+// so nothing else can be queued ahead of it: AUTHENTICATED for players, then the
+// requested SNAPSHOT, the deltas a resuming client missed (or RESYNC) and the current version
+func (h *Hub) sendInitialState(client *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
 
 	currentVersion, err := h.redisRepo.GetLeaderboardVersion(ctx)
 	if err != nil {
 		log.Printf("❌ Failed to get initial version: %v", err)
 		return
 	}
+	client.initialVersion = currentVersion
 
 	// Update lastVersion if this is the first client
-	if h.lastVersion == 0 {
-		h.lastVersion = currentVersion
+	h.lastVersion.CompareAndSwap(0, currentVersion)
+
+	// Confirm who the connection is authenticated as
+	if client.opts.Player != "" {
+		client.queue(controlFrame("AUTHENTICATED", "", client.opts.Player))
+	}
+
+	if client.opts.SnapshotStart > 0 {
+		h.sendSnapshot(ctx, client, currentVersion)
+	}
+
+	if client.opts.LastVersion > 0 {
+		h.resumeSession(ctx, client, currentVersion)
+	}
+
+	if client.queue(versionFrame("VERSION_UPDATE", currentVersion)) {
+		log.Printf("✅ Sent initial version (%d) to new client", currentVersion)
+	} else {
+		log.Println("⚠️ Timeout sending initial version - client may be slow")
 	}
 
-	// Create version update message
-	update := VersionUpdate{
-		Type:    "VERSION_UPDATE",
-		Version: currentVersion,
+	// Current viewer count, so the client doesn't wait for it to change
+	if presence := h.presence.get(); !presence.UpdatedAt.IsZero() {
+		client.queue(presenceFrame(PresenceBoard, presence.Viewers))
 	}
+}
+
+// sendSnapshot pushes the requested page, shared with every client asking for it at this version
+func (h *Hub) sendSnapshot(ctx context.Context, client *Client, version int64) {
+	start, end := client.opts.SnapshotStart, client.opts.SnapshotEnd
 
-	// Marshal to JSON
-	message, err := json.Marshal(update)
+	viewer := h.viewer(ctx, client)
+	message, entries, err := h.snapshots.get(ctx, viewer, start-1, end-start+1, version)
 	if err != nil {
-		log.Printf("❌ Failed to marshal initial version: %v", err)
+		log.Printf("⚠️ Failed to build snapshot for ranks %d-%d: %v", start, end, err)
 		return
 	}
+	client.queue(message)
+
+	if client.opts.SubscribeSnapshot {
+		client.snapshotEntries = entries
+		client.snapshotViewer = viewer
+	}
+}
+
+// viewer returns the client's player when they are shadow-banned, "" otherwise
+// A shadow-banned player still sees themselves, so their reads are not shared with other
+// clients. The status is checked as each snapshot, subscription or watch is set up.
+func (h *Hub) viewer(ctx context.Context, client *Client) string {
+	if client.opts.Player != "" && h.reader.IsShadowBanned(ctx, client.opts.Player) {
+		return client.opts.Player
+	}
+	return ""
+}
 
-	// Check if client is still registered before sending
-	h.mu.RLock()
-	_, exists := h.clients[client]
-	h.mu.RUnlock()
+// resumeSession replays the changes a reconnecting client missed since its last-seen version
+// If the changelog no longer covers that version the client is told to do a full resync
+func (h *Hub) resumeSession(ctx context.Context, client *Client, currentVersion int64) {
+	since := client.opts.LastVersion
+	resync := versionFrame("RESYNC", currentVersion)
 
-	if !exists {
-		log.Println("⚠️ Client disconnected before initial version could be sent")
+	// A last-seen version ahead of ours means Redis was reseeded
+	if since > currentVersion {
+		client.queue(resync)
 		return
 	}
 
-	// Send to client with timeout to prevent blocking
-	select {
-	case client.send <- message:
-		log.Printf("✅ Sent initial version (%d) to new client", currentVersion)
-	case <-time.After(2 * time.Second):
-		log.Println("⚠️ Timeout sending initial version - client may be slow")
+	for page := 0; page < maxResumePages; page++ {
+		changes, err := h.reader.GetChangesSince(ctx, since)
+		if err != nil || changes.ResyncRequired {
+			client.queue(resync)
+			return
+		}
+
+		client.queue(newFrame("DELTA", changes.Version, DeltaMessage{
+			Type:    "DELTA",
+			Since:   changes.Since,
+			Version: changes.Version,
+			Changes: changes.Changes,
+		}))
+
+		if !changes.HasMore {
+			log.Printf("🔁 Resumed session from version %d", client.opts.LastVersion)
+			return
+		}
+		since = changes.Version
+	}
+
+	// Too far behind to be worth replaying
+	client.queue(resync)
+}
+
+// subscribeSnapshotRange subscribes a registered client to the range it received as a snapshot
+func (h *Hub) subscribeSnapshotRange(client *Client) {
+	spec := subscriptionSpec{
+		kind:   SubscriptionRange,
+		start:  client.opts.SnapshotStart,
+		end:    client.opts.SnapshotEnd,
+		viewer: client.snapshotViewer,
+	}
+	if err := h.subs.subscribe(client, spec, client.initialVersion, client.snapshotEntries); err != nil {
+		h.trySend(client, controlFrame("ERROR", spec.key(), err.Error()))
 	}
 }
 
 // GetClientCount returns the current number of connected clients
 func (h *Hub) GetClientCount() int {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return len(h.clients)
+	return int(h.clientCount.Load())
+}
+
+// shardFor returns the shard a client belongs to
+func (h *Hub) shardFor(client *Client) *hubShard {
+	return h.shards[client.id%uint64(len(h.shards))]
+}
+
+// register adds a client to its shard
+func (h *Hub) register(client *Client) {
+	h.shardFor(client).add(client)
+	total := h.clientCount.Add(1)
+	log.Printf("✅ Client connected (Total: %d)", total)
+
+	// A broadcast may have gone out between the handshake and registration
+	if version := h.lastVersion.Load(); version > client.initialVersion {
+		h.trySend(client, versionFrame("VERSION_UPDATE", version))
+	}
+}
+
+// unregister removes a client from its shard and drops its subscriptions
+// Safe to call more than once
+func (h *Hub) unregister(client *Client) {
+	if !h.shardFor(client).remove(client) {
+		return
+	}
+	total := h.clientCount.Add(-1)
+	h.subs.removeClient(client)
+	h.watches.removeClient(client)
+	log.Printf("❌ Client disconnected (Total: %d)", total)
+}
+
+// Version returns the latest leaderboard version the hub has broadcast
+func (h *Hub) Version() int64 {
+	return h.lastVersion.Load()
+}
+
+// GetMetrics returns a snapshot of the hub's client and delivery metrics
+func (h *Hub) GetMetrics() map[string]interface{} {
+	byTransport := map[string]int{
+		TransportWebSocket: 0,
+		TransportSSE:       0,
+		TransportGRPC:      0,
+		TransportGraphQL:   0,
+	}
+
+	byEncoding := map[string]int{
+		EncodingJSON:    0,
+		EncodingMsgPack: 0,
+	}
+
+	total := 0
+	for _, shard := range h.shards {
+		shard.each(func(client *Client) {
+			byTransport[client.transport]++
+			byEncoding[client.encoding]++
+			total++
+		})
+	}
+
+	return map[string]interface{}{
+		"clients":              total,
+		"shards":               len(h.shards),
+		"clients_by_transport": byTransport,
+		"clients_by_encoding":  byEncoding,
+		"version":              h.lastVersion.Load(),
+		"broadcasts":           h.metrics.broadcasts.Load(),
+		"messages_sent":        h.metrics.sent.Load(),
+		"messages_dropped":     h.metrics.dropped.Load(),
+		"long_poll_waiters":    h.metrics.waiters.Load(),
+		"evictions":            h.metrics.evictions.Load(),
+		"lag":                  h.lagReport(),
+	}
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.unregister(c)
 		c.conn.Close()
 	}()
 
-	// Don't set read deadline or limits for browser WebSocket clients
-	// Browser WebSockets handle ping/pong automatically at the protocol level
-	// and don't expose pong handling to JavaScript
-	
+	// Browsers answer pings automatically at the protocol level, so every client
+	// keeps the read deadline moving; a peer that stops answering is dropped
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
 	for {
-		_, _, err := c.conn.ReadMessage()
+		messageType, data, err := c.conn.ReadMessage()
 		if err != nil {
 			// Client disconnected or error occurred
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
@@ -236,15 +627,17 @@ func (c *Client) readPump() {
 			}
 			break
 		}
-		// We don't expect messages from clients, but if we receive any, just ignore them
+
+		// Subscription requests (see ClientMessage)
+		c.hub.handleClientMessage(c, messageType == websocket.BinaryMessage, data)
 	}
 }
 
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *Client) writePump() {
-	// Don't use ping ticker for browser clients
-	// Browser WebSockets handle keepalive at the protocol level
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
+		ticker.Stop()
 		c.conn.Close()
 	}()
 
@@ -258,39 +651,128 @@ func (c *Client) writePump() {
 				return
 			}
 
+			// Binary messages can't be newline-delimited, so each goes in its own websocket message
+			if c.encoding == EncodingMsgPack {
+				if err := c.writeBinary(message); err != nil {
+					return
+				}
+				continue
+			}
+
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
 			}
-			w.Write(message)
+			w.Write(message.data)
+			c.markDelivered(message)
 
 			// Add queued messages to the current websocket message
 			n := len(c.send)
 			for i := 0; i < n; i++ {
+				next, ok := <-c.send
+				if !ok {
+					break
+				}
 				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				w.Write(next.data)
+				c.markDelivered(next)
 			}
 
 			if err := w.Close(); err != nil {
 				return
 			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-c.evicted:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"))
+			return
 		}
 	}
 }
 
-// ServeWS handles WebSocket requests from clients
-func ServeWS(hub *Hub, conn *websocket.Conn) {
-	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
+// writeBinary writes a message and any others already queued as MessagePack
+func (c *Client) writeBinary(message *frame) error {
+	n := len(c.send)
+	for i := 0; ; i++ {
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, message.payload(EncodingMsgPack)); err != nil {
+			return err
+		}
+		c.markDelivered(message)
+
+		if i == n {
+			return nil
+		}
+		next, ok := <-c.send
+		if !ok {
+			return nil
+		}
+		message = next
 	}
-	
-	client.hub.register <- client
+}
 
+// ServeWS handles WebSocket requests from clients
+// The message encoding follows the subprotocol negotiated during the upgrade (see Subprotocols)
+func ServeWS(hub *Hub, conn *websocket.Conn, opts ConnectOptions) {
+	client := newClient(hub, TransportWebSocket, opts)
+	client.conn = conn
+	client.encoding = encodingForSubprotocol(conn.Subprotocol())
+	
 	// Start write pump in goroutine
 	go client.writePump()
+
+	hub.connect(client)
 	
 	// Run read pump in current goroutine (blocks until disconnect)
 	client.readPump()
 }
+
+// newClient creates an unregistered client
+func newClient(hub *Hub, transport string, opts ConnectOptions) *Client {
+	return &Client{
+		id:        hub.nextClientID.Add(1),
+		hub:       hub,
+		transport: transport,
+		encoding:  EncodingJSON,
+		send:      make(chan *frame, clientSendBuffer),
+		evicted:   make(chan struct{}),
+		opts:      opts,
+	}
+}
+
+// connect sends a new client its handshake messages and registers it with the hub
+// Handshake messages are queued before registering so they are always first
+func (h *Hub) connect(client *Client) {
+	h.sendInitialState(client)
+
+	h.register(client)
+
+	if client.opts.SubscribeSnapshot {
+		h.subscribeSnapshotRange(client)
+	}
+}
+
+// queue sends a handshake message before the client is registered with the hub
+// Only the connecting goroutine holds the client then, so the send channel cannot be closed yet
+func (c *Client) queue(message *frame) bool {
+	if message == nil {
+		return false
+	}
+
+	select {
+	case c.send <- message:
+		c.accepted()
+		return true
+	case <-time.After(writeWait):
+		// A client that can't take its handshake within writeWait is not going to keep up
+		c.hub.metrics.dropped.Add(1)
+		c.evict("handshake timed out")
+		return false
+	}
+}
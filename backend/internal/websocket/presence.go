@@ -0,0 +1,182 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval between presence heartbeats, each publishing this instance's counts
+	presenceHeartbeatInterval = 5 * time.Second
+
+	// Counts from an instance that missed this many seconds of heartbeats are ignored
+	presenceTTL = 3 * presenceHeartbeatInterval
+
+	// PresenceBoard is the scope counting every viewer of the leaderboard
+	PresenceBoard = "board"
+)
+
+// PresenceMessage is pushed when the number of viewers of a scope changes
+// Scope is PresenceBoard (sent to every client) or a range subscription key
+// (sent to the clients subscribed to it)
+type PresenceMessage struct {
+	Type    string `json:"type"`
+	Scope   string `json:"scope"`
+	Viewers int64  `json:"viewers"`
+}
+
+// Presence is the number of viewers across every server instance
+type Presence struct {
+	Viewers   int64            `json:"viewers"`
+	Ranges    map[string]int64 `json:"ranges"`
+	Instances int              `json:"instances"`
+	LocalOnly bool             `json:"local_only,omitempty"` // Redis unavailable, counts cover this instance only
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// presenceTracker publishes this instance's viewer counts to Redis and keeps the
+// aggregate across instances, pushing changes to clients
+type presenceTracker struct {
+	hub        *Hub
+	instanceID string
+
+	mu      sync.RWMutex
+	current Presence
+}
+
+// newPresenceTracker creates a tracker with a unique instance id
+func newPresenceTracker(hub *Hub) *presenceTracker {
+	return &presenceTracker{
+		hub:        hub,
+		instanceID: newInstanceID(),
+		current:    Presence{Ranges: map[string]int64{}},
+	}
+}
+
+// newInstanceID identifies this server process in presence heartbeats
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// run sends heartbeats until ctx is done, then withdraws this instance's counts
+func (p *presenceTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(presenceHeartbeatInterval)
+	defer ticker.Stop()
+
+	p.heartbeat(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			cleanupCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := p.hub.redisRepo.RemovePresence(cleanupCtx, p.instanceID); err != nil {
+				log.Printf("⚠️ Failed to remove presence: %v", err)
+			}
+			return
+		case <-ticker.C:
+			p.heartbeat(ctx)
+		}
+	}
+}
+
+// heartbeat publishes local counts, reads the aggregate and pushes what changed
+func (p *presenceTracker) heartbeat(ctx context.Context) {
+	local := p.hub.subs.rangeViewers()
+	local[PresenceBoard] = int64(p.hub.GetClientCount())
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	counts, instances, err := p.aggregate(ctx, local)
+	next := Presence{
+		Viewers:   counts[PresenceBoard],
+		Ranges:    make(map[string]int64, len(counts)),
+		Instances: instances,
+		UpdatedAt: time.Now(),
+	}
+	if err != nil {
+		// Fall back to this instance's counts rather than showing nothing
+		log.Printf("⚠️ Failed to aggregate presence: %v", err)
+		next.LocalOnly = true
+	}
+	for scope, count := range counts {
+		if scope != PresenceBoard {
+			next.Ranges[scope] = count
+		}
+	}
+
+	p.mu.Lock()
+	previous := p.current
+	p.current = next
+	p.mu.Unlock()
+
+	p.push(previous, next)
+}
+
+// aggregate publishes local counts and returns the totals across instances
+// On failure it returns the local counts
+func (p *presenceTracker) aggregate(ctx context.Context, local map[string]int64) (map[string]int64, int, error) {
+	if err := p.hub.redisRepo.PublishPresence(ctx, p.instanceID, local, presenceTTL); err != nil {
+		return local, 1, err
+	}
+	counts, instances, err := p.hub.redisRepo.GetPresence(ctx, presenceTTL)
+	if err != nil {
+		return local, 1, err
+	}
+	return counts, instances, nil
+}
+
+// push sends PRESENCE for every scope whose count changed
+func (p *presenceTracker) push(previous, next Presence) {
+	if next.Viewers != previous.Viewers {
+		if message := presenceFrame(PresenceBoard, next.Viewers); message != nil {
+			for _, shard := range p.hub.shards {
+				shard.publish(message)
+			}
+		}
+	}
+
+	for scope, viewers := range next.Ranges {
+		if previous.Ranges[scope] == viewers {
+			continue
+		}
+		message := presenceFrame(scope, viewers)
+		for _, client := range p.hub.subs.groupClients(scope) {
+			p.hub.trySend(client, message)
+		}
+	}
+}
+
+// presenceFrame builds a PRESENCE message
+func presenceFrame(scope string, viewers int64) *frame {
+	return newFrame("PRESENCE", 0, PresenceMessage{Type: "PRESENCE", Scope: scope, Viewers: viewers})
+}
+
+// get returns a copy of the latest aggregate
+func (p *presenceTracker) get() Presence {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	presence := p.current
+	presence.Ranges = make(map[string]int64, len(p.current.Ranges))
+	for scope, count := range p.current.Ranges {
+		presence.Ranges[scope] = count
+	}
+	return presence
+}
+
+// GetPresence returns the number of viewers across all instances, refreshed every heartbeat
+func (h *Hub) GetPresence() Presence {
+	return h.presence.get()
+}
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// hubShard is an independent slice of the hub's clients with its own lock and
+// broadcast goroutine, so fan-out to a large number of clients runs in parallel and
+// registering a client only contends with the clients of its shard
+type hubShard struct {
+	mu      sync.RWMutex
+	clients map[*Client]bool
+
+	// Latest broadcast of each event type not yet fanned out; a newer broadcast of the
+	// same type replaces it, since clients only need the latest version or count
+	pendingMu sync.Mutex
+	pending   map[string]*frame
+	wake      chan struct{}
+}
+
+// newShards creates n shards, defaulting to one per CPU
+func newShards(n int) []*hubShard {
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	shards := make([]*hubShard, n)
+	for i := range shards {
+		shards[i] = &hubShard{
+			clients: make(map[*Client]bool),
+			pending: make(map[string]*frame),
+			wake:    make(chan struct{}, 1),
+		}
+	}
+	return shards
+}
+
+// run fans out broadcasts until ctx is done
+func (s *hubShard) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+			s.pendingMu.Lock()
+			pending := s.pending
+			s.pending = make(map[string]*frame, len(pending))
+			s.pendingMu.Unlock()
+
+			for _, message := range pending {
+				s.fanOut(message)
+			}
+		}
+	}
+}
+
+// publish hands a broadcast to the shard's goroutine without waiting for the fan-out
+func (s *hubShard) publish(message *frame) {
+	s.pendingMu.Lock()
+	s.pending[message.event] = message
+	s.pendingMu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// Already signalled; the goroutine will pick up the latest frame
+	}
+}
+
+// fanOut queues a shared frame for every client in the shard
+func (s *hubShard) fanOut(message *frame) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for client := range s.clients {
+		select {
+		case client.send <- message:
+			client.accepted()
+		default:
+			// Client's send buffer is full, skip this client (evicted if it keeps happening)
+			client.dropped()
+		}
+	}
+}
+
+// add registers a client with the shard
+func (s *hubShard) add(client *Client) {
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+}
+
+// remove unregisters a client and closes its send channel
+// Returns false if the client was not registered (already removed)
+func (s *hubShard) remove(client *Client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[client]; !ok {
+		return false
+	}
+	delete(s.clients, client)
+	close(client.send)
+	return true
+}
+
+// len returns the number of clients in the shard
+func (s *hubShard) len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.clients)
+}
+
+// each calls fn for every client in the shard under its read lock
+func (s *hubShard) each(fn func(client *Client)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for client := range s.clients {
+		fn(client)
+	}
+}
@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"backend/internal/models"
+)
+
+// Maximum number of distinct pages kept in the snapshot cache
+const maxSnapshotCacheEntries = 256
+
+// SnapshotMessage is the leaderboard page pushed as a client's first message when requested
+// It replaces the deprecated full-board LeaderboardUpdate push
+type SnapshotMessage struct {
+	Type    string                    `json:"type"`
+	Version int64                     `json:"version"`
+	Offset  int                       `json:"offset"`
+	Limit   int                       `json:"limit"`
+	Total   int64                     `json:"total"`
+	Data    []models.LeaderboardEntry `json:"data"`
+}
+
+// snapshotKey identifies a cached page
+type snapshotKey struct {
+	offset int
+	limit  int
+}
+
+// snapshotEntry is a page encoded at a given version
+// Its mutex is held while the page is fetched so concurrent requests share one read
+type snapshotEntry struct {
+	mu      sync.Mutex
+	version int64
+	message *frame
+	data    []models.LeaderboardEntry
+}
+
+// snapshotCache encodes each requested page once per version and shares it across clients,
+// so a reconnect storm costs one leaderboard read per page per version
+type snapshotCache struct {
+	reader LeaderboardReader
+
+	mu      sync.Mutex
+	entries map[snapshotKey]*snapshotEntry
+}
+
+// newSnapshotCache creates an empty snapshot cache
+func newSnapshotCache(reader LeaderboardReader) *snapshotCache {
+	return &snapshotCache{
+		reader:  reader,
+		entries: make(map[snapshotKey]*snapshotEntry),
+	}
+}
+
+// get returns the encoded SNAPSHOT for a page at version (or newer) and its entries
+// viewer is "" for the shared public page, or a shadow-banned player whose page bypasses the cache
+func (c *snapshotCache) get(ctx context.Context, viewer string, offset, limit int, version int64) (*frame, []models.LeaderboardEntry, error) {
+	if viewer != "" {
+		return c.fetch(ctx, viewer, offset, limit, version)
+	}
+
+	entry := c.entry(snapshotKey{offset: offset, limit: limit}, version)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.message == nil || entry.version < version {
+		message, data, err := c.fetch(ctx, "", offset, limit, version)
+		if err != nil {
+			return nil, nil, err
+		}
+		entry.version = version
+		entry.message = message
+		entry.data = data
+	}
+
+	return entry.message, entry.data, nil
+}
+
+// fetch reads a page as seen by viewer and encodes it as a SNAPSHOT
+func (c *snapshotCache) fetch(ctx context.Context, viewer string, offset, limit int, version int64) (*frame, []models.LeaderboardEntry, error) {
+	page, err := c.reader.GetLeaderboardFor(ctx, viewer, offset, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+
+	message := newFrame("SNAPSHOT", version, SnapshotMessage{
+		Type:    "SNAPSHOT",
+		Version: version,
+		Offset:  page.Offset,
+		Limit:   page.Limit,
+		Total:   page.Total,
+		Data:    page.Data,
+	})
+	return message, page.Data, nil
+}
+
+// entry returns the cache entry for key, evicting stale pages when the cache is full
+func (c *snapshotCache) entry(key snapshotKey, version int64) *snapshotEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		return entry
+	}
+
+	if len(c.entries) >= maxSnapshotCacheEntries {
+		for k, e := range c.entries {
+			// TryLock skips entries being refreshed right now
+			if e.mu.TryLock() {
+				stale := e.version < version
+				e.mu.Unlock()
+				if stale {
+					delete(c.entries, k)
+				}
+			}
+		}
+	}
+
+	entry := &snapshotEntry{}
+	c.entries[key] = entry
+	return entry
+}
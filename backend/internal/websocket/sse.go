@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"bufio"
+	"strconv"
+	"time"
+)
+
+// Interval between SSE comment lines, which keep proxies from closing idle streams
+// and detect disconnected clients (the flush fails)
+const sseKeepAliveInterval = 15 * time.Second
+
+// ServeSSE streams hub messages to a Server-Sent Events client until it disconnects
+// The client shares the hub's registry with WebSocket clients and receives the same
+// messages; each message's type is the SSE event name and its version the event id,
+// so a reconnecting EventSource resumes through Last-Event-ID
+func ServeSSE(hub *Hub, w *bufio.Writer, opts ConnectOptions) {
+	client := newClient(hub, TransportSSE, opts)
+
+	// Handshake messages fit in the send buffer, so they can be queued before the write loop starts
+	hub.connect(client)
+	defer func() {
+		hub.unregister(client)
+	}()
+
+	// Tell EventSource how long to wait before reconnecting
+	w.WriteString("retry: 3000\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(sseKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				// The hub closed the channel
+				return
+			}
+			writeSSEEvent(w, message)
+			client.markDelivered(message)
+
+			// Add queued messages to the current flush
+			n := len(client.send)
+			for i := 0; i < n; i++ {
+				next, ok := <-client.send
+				if !ok {
+					break
+				}
+				writeSSEEvent(w, next)
+				client.markDelivered(next)
+			}
+
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			w.WriteString(": keepalive\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+		case <-client.evicted:
+			// EventSource reconnects on its own and resumes through Last-Event-ID
+			if message := controlFrame("ERROR", "", "slow consumer"); message != nil {
+				writeSSEEvent(w, message)
+				w.Flush()
+			}
+			return
+		}
+	}
+}
+
+// writeSSEEvent writes a frame as a single SSE event
+// JSON encoding never contains raw newlines, so the data fits on one line
+func writeSSEEvent(w *bufio.Writer, message *frame) {
+	if message.version > 0 {
+		w.WriteString("id: ")
+		w.WriteString(strconv.FormatInt(message.version, 10))
+		w.WriteByte('\n')
+	}
+	w.WriteString("event: ")
+	w.WriteString(message.event)
+	w.WriteString("\ndata: ")
+	w.Write(message.data)
+	w.WriteString("\n\n")
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+)
+
+// Transports for in-process consumers of the hub
+const (
+	TransportGRPC    = "grpc"
+	TransportGraphQL = "graphql"
+)
+
+// ErrStreamClosed is returned by Stream.Next once the hub has dropped the stream
+var ErrStreamClosed = errors.New("stream closed by hub")
+
+// ErrSlowConsumer is returned by Stream.Next when the stream was evicted for falling behind
+var ErrSlowConsumer = errors.New("stream evicted as a slow consumer")
+
+// Event is a hub message delivered to an in-process stream
+// Message is one of VersionUpdate, DeltaMessage, SnapshotMessage, SubscriptionUpdate,
+// RankChanged, PresenceMessage or ControlMessage
+type Event struct {
+	Type    string
+	Version int64
+	Message interface{}
+}
+
+// Stream is a hub client consumed in-process rather than over a socket, used by the
+// gRPC and GraphQL servers. It shares the hub's registry, metrics and slow-consumer
+// policy with WebSocket and SSE clients.
+type Stream struct {
+	client *Client
+}
+
+// OpenStream connects an in-process client: it receives the same handshake messages
+// as a WebSocket client with these options, then every broadcast
+// The caller must Close the stream
+func (h *Hub) OpenStream(transport string, opts ConnectOptions) *Stream {
+	client := newClient(h, transport, opts)
+	h.connect(client)
+	return &Stream{client: client}
+}
+
+// Next blocks until the next event, ctx is done or the hub drops the stream
+func (s *Stream) Next(ctx context.Context) (Event, error) {
+	select {
+	case message, ok := <-s.client.send:
+		if !ok {
+			return Event{}, ErrStreamClosed
+		}
+		s.client.markDelivered(message)
+		return Event{Type: message.event, Version: message.version, Message: message.message}, nil
+	case <-s.client.evicted:
+		return Event{}, ErrSlowConsumer
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Close unregisters the stream from the hub
+func (s *Stream) Close() {
+	s.client.hub.unregister(s.client)
+}
+
+// Watch registers the stream for RANK_CHANGED notifications about username, like a
+// WebSocket watch action. topN of 0 reports rank changes only.
+func (s *Stream) Watch(username string, topN int) error {
+	return s.client.hub.watches.watch(s.client, username, topN)
+}
@@ -0,0 +1,501 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repository"
+)
+
+const (
+	// Maximum number of subscriptions a single client may hold
+	maxSubscriptionsPerClient = 10
+
+	// Maximum number of distinct subscriptions across all clients, each re-resolved on
+	// every version
+	maxSubscriptionGroups = 2000
+
+	// Subscriptions resolved concurrently when a new version is evaluated
+	resolveWorkers = 16
+
+	// Maximum number of ranks a range subscription may cover (matches the HTTP page limit)
+	maxRangeSize = 100
+
+	// Maximum radius of an "around me" subscription
+	maxAroundRadius = 25
+
+	// Timeout for resolving a single subscription against the leaderboard
+	resolveTimeout = 5 * time.Second
+)
+
+// Subscription types accepted in ClientMessage.Type
+const (
+	SubscriptionRange  = "range"
+	SubscriptionUser   = "user"
+	SubscriptionAround = "around"
+)
+
+// LeaderboardReader is the part of the leaderboard service the hub needs to resolve
+// subscriptions and replay missed changes
+// Reads take a viewer: "" for the public leaderboard shared by every client, or a
+// shadow-banned player, who alone still sees themselves
+type LeaderboardReader interface {
+	IsShadowBanned(ctx context.Context, viewer string) bool
+	GetLeaderboardFor(ctx context.Context, viewer string, offset, limit int) (*models.LeaderboardResponse, error)
+	SearchUserFor(ctx context.Context, viewer, username string) (*models.SearchResponse, error)
+	GetChangesSince(ctx context.Context, since int64) (*models.ChangesResponse, error)
+	GetUserEntriesFor(ctx context.Context, viewer string, usernames []string) (map[string]models.LeaderboardEntry, error)
+}
+
+// ClientMessage is a message sent from a client to the hub
+//
+//	{"action":"subscribe","type":"range","start":1,"end":50}
+//	{"action":"subscribe","type":"user","username":"user_42"}
+//	{"action":"subscribe","type":"around","username":"user_42","radius":5}
+//	{"action":"unsubscribe","subscription":"range:1:50"}
+//	{"action":"watch","username":"user_42","top_n":10}
+//	{"action":"unwatch","username":"user_42"}
+type ClientMessage struct {
+	Action       string `json:"action"`
+	Subscription string `json:"subscription,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Start        int    `json:"start,omitempty"`
+	End          int    `json:"end,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Radius       int    `json:"radius,omitempty"`
+	TopN         int    `json:"top_n,omitempty"`
+}
+
+// SubscriptionUpdate carries the current entries of a subscription
+// Sent once on subscribe and again whenever the entries change
+type SubscriptionUpdate struct {
+	Type         string                    `json:"type"`
+	Subscription string                    `json:"subscription"`
+	Version      int64                     `json:"version"`
+	Entries      []models.LeaderboardEntry `json:"entries"`
+}
+
+// ControlMessage acknowledges a client request or reports an error
+type ControlMessage struct {
+	Type         string `json:"type"`
+	Subscription string `json:"subscription,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// subscriptionSpec is a validated subscription request
+type subscriptionSpec struct {
+	kind     string
+	start    int
+	end      int
+	username string
+	radius   int
+	viewer   string // Shadow-banned player the entries are resolved for, "" for everyone
+}
+
+// key returns the canonical name shared by every client with the same subscription
+func (s subscriptionSpec) key() string {
+	switch s.kind {
+	case SubscriptionRange:
+		return fmt.Sprintf("range:%d:%d", s.start, s.end)
+	case SubscriptionUser:
+		return "user:" + s.username
+	default:
+		return fmt.Sprintf("around:%s:%d", s.username, s.radius)
+	}
+}
+
+// forViewer resolves the subscription for viewer when their view differs from the public one:
+// a shadow-banned viewer sees themselves in every range and around subscription, and in
+// their own user subscription
+func (s subscriptionSpec) forViewer(viewer string) subscriptionSpec {
+	if s.kind != SubscriptionUser || s.username == viewer {
+		s.viewer = viewer
+	}
+	return s
+}
+
+// groupKey returns the key of the group resolving this subscription
+// Clients share a group unless the subscription is resolved for a shadow-banned viewer
+func (s subscriptionSpec) groupKey() string {
+	if s.viewer == "" {
+		return s.key()
+	}
+	return s.key() + "@" + s.viewer
+}
+
+// parseSubscription validates a subscribe message
+func parseSubscription(msg ClientMessage) (subscriptionSpec, error) {
+	spec := subscriptionSpec{kind: msg.Type}
+
+	switch msg.Type {
+	case SubscriptionRange:
+		if msg.Start < 1 || msg.End < msg.Start {
+			return spec, fmt.Errorf("range requires 1 <= start <= end")
+		}
+		if msg.End-msg.Start+1 > maxRangeSize {
+			return spec, fmt.Errorf("range may cover at most %d ranks", maxRangeSize)
+		}
+		spec.start, spec.end = msg.Start, msg.End
+
+	case SubscriptionUser:
+		if msg.Username == "" {
+			return spec, fmt.Errorf("user subscription requires a username")
+		}
+		spec.username = msg.Username
+
+	case SubscriptionAround:
+		if msg.Username == "" {
+			return spec, fmt.Errorf("around subscription requires a username")
+		}
+		if msg.Radius < 1 || msg.Radius > maxAroundRadius {
+			return spec, fmt.Errorf("radius must be between 1 and %d", maxAroundRadius)
+		}
+		spec.username, spec.radius = msg.Username, msg.Radius
+
+	default:
+		return spec, fmt.Errorf("unknown subscription type %q", msg.Type)
+	}
+
+	return spec, nil
+}
+
+// subscriptionGroup is the shared state of every client subscribed to the same key
+type subscriptionGroup struct {
+	spec        subscriptionSpec
+	clients     map[*Client]bool
+	lastEntries []byte // JSON of the last pushed entries, used for change detection
+	lastMessage *frame // Last SUBSCRIPTION_UPDATE, replayed to new subscribers
+}
+
+// subscriptionManager tracks subscriptions and pushes entries when they change
+// It runs its own goroutine so resolving subscriptions never blocks the hub loop
+type subscriptionManager struct {
+	hub    *Hub
+	reader LeaderboardReader
+
+	mu       sync.Mutex
+	groups   map[string]*subscriptionGroup // Group key -> group
+	byClient map[*Client]map[string]string // Client -> subscription key -> group key
+
+	// Latest version to evaluate; buffered so bursts collapse into one pass
+	versions chan int64
+}
+
+// newSubscriptionManager creates an empty subscription manager
+func newSubscriptionManager(hub *Hub, reader LeaderboardReader) *subscriptionManager {
+	return &subscriptionManager{
+		hub:      hub,
+		reader:   reader,
+		groups:   make(map[string]*subscriptionGroup),
+		byClient: make(map[*Client]map[string]string),
+		versions: make(chan int64, 1),
+	}
+}
+
+// run evaluates subscriptions whenever a new version is signalled
+func (m *subscriptionManager) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case version := <-m.versions:
+			m.evaluate(ctx, version)
+		}
+	}
+}
+
+// notify signals a new version, replacing any version still waiting to be evaluated
+func (m *subscriptionManager) notify(version int64) {
+	notifyLatest(m.versions, version)
+}
+
+// notifyLatest puts version on a 1-slot channel, replacing any version still waiting
+func notifyLatest(versions chan int64, version int64) {
+	select {
+	case versions <- version:
+		return
+	default:
+	}
+
+	select {
+	case <-versions:
+	default:
+	}
+	select {
+	case versions <- version:
+	default:
+	}
+}
+
+// subscribe registers a client for spec and sends it the current entries
+// known holds entries the client already has (from a handshake snapshot), or nil
+func (m *subscriptionManager) subscribe(client *Client, spec subscriptionSpec, version int64, known []models.LeaderboardEntry) error {
+	key, groupKey := spec.key(), spec.groupKey()
+
+	m.mu.Lock()
+	subs := m.byClient[client]
+	if _, ok := subs[key]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("already subscribed to %s", key)
+	}
+	if len(subs) >= maxSubscriptionsPerClient {
+		m.mu.Unlock()
+		return fmt.Errorf("at most %d subscriptions per connection", maxSubscriptionsPerClient)
+	}
+
+	group, exists := m.groups[groupKey]
+	if !exists {
+		if len(m.groups) >= maxSubscriptionGroups {
+			m.mu.Unlock()
+			return fmt.Errorf("server holds the maximum of %d distinct subscriptions, try again later", maxSubscriptionGroups)
+		}
+		group = &subscriptionGroup{spec: spec, clients: make(map[*Client]bool)}
+		m.groups[groupKey] = group
+	}
+	if subs == nil {
+		subs = make(map[string]string)
+		m.byClient[client] = subs
+	}
+	subs[key] = groupKey
+	group.clients[client] = true
+	cached := group.lastMessage
+	cachedEntries := group.lastEntries
+	m.mu.Unlock()
+
+	m.hub.trySend(client, controlFrame("SUBSCRIBED", key, ""))
+
+	if known != nil {
+		if cached == nil {
+			// New group: the snapshot becomes its baseline
+			m.record(groupKey, version, known)
+		} else if !bytes.Equal(cachedEntries, encodeMessage(known)) {
+			m.hub.trySend(client, cached)
+		}
+		return nil
+	}
+
+	if cached != nil {
+		m.hub.trySend(client, cached)
+		return nil
+	}
+
+	// First subscriber: resolve now so the client doesn't wait for the next change
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	entries, err := m.resolve(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", key, err)
+	}
+	// Another subscriber may have resolved the group concurrently; send whatever is current
+	if message, _ := m.record(groupKey, version, entries); message != nil {
+		m.hub.trySend(client, message)
+	}
+	return nil
+}
+
+// unsubscribe removes a client's subscription
+func (m *subscriptionManager) unsubscribe(client *Client, key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groupKey, ok := m.byClient[client][key]
+	if !ok {
+		return false
+	}
+	delete(m.byClient[client], key)
+	m.removeFromGroupLocked(client, groupKey)
+	return true
+}
+
+// removeClient drops every subscription held by a disconnected client
+func (m *subscriptionManager) removeClient(client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, groupKey := range m.byClient[client] {
+		m.removeFromGroupLocked(client, groupKey)
+	}
+	delete(m.byClient, client)
+}
+
+// removeFromGroupLocked removes a client from a group, deleting the group when empty
+func (m *subscriptionManager) removeFromGroupLocked(client *Client, groupKey string) {
+	group, ok := m.groups[groupKey]
+	if !ok {
+		return
+	}
+	delete(group.clients, client)
+	if len(group.clients) == 0 {
+		delete(m.groups, groupKey)
+	}
+}
+
+// rangeViewers returns the number of local clients subscribed to each range
+func (m *subscriptionManager) rangeViewers() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, group := range m.groups {
+		if group.spec.kind == SubscriptionRange {
+			counts[group.spec.key()] += int64(len(group.clients))
+		}
+	}
+	return counts
+}
+
+// groupClients returns the clients currently in the group groupKey
+func (m *subscriptionManager) groupClients(groupKey string) []*Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[groupKey]
+	if !ok {
+		return nil
+	}
+	clients := make([]*Client, 0, len(group.clients))
+	for client := range group.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// evaluate re-resolves every subscription group and pushes those whose entries changed
+// Groups are resolved by up to resolveWorkers goroutines, so one pass takes about
+// groups/resolveWorkers round trips rather than one per group
+func (m *subscriptionManager) evaluate(ctx context.Context, version int64) {
+	m.mu.Lock()
+	specs := make([]subscriptionSpec, 0, len(m.groups))
+	for _, group := range m.groups {
+		specs = append(specs, group.spec)
+	}
+	m.mu.Unlock()
+
+	queue := make(chan subscriptionSpec)
+	var wg sync.WaitGroup
+	for i := 0; i < min(resolveWorkers, len(specs)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for spec := range queue {
+				m.evaluateGroup(ctx, version, spec)
+			}
+		}()
+	}
+
+	for _, spec := range specs {
+		queue <- spec
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// evaluateGroup re-resolves one subscription group and pushes it if its entries changed
+func (m *subscriptionManager) evaluateGroup(ctx context.Context, version int64, spec subscriptionSpec) {
+	groupKey := spec.groupKey()
+	resolveCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	entries, err := m.resolve(resolveCtx, spec)
+	cancel()
+	if err != nil {
+		log.Printf("⚠️ Failed to resolve subscription %s: %v", groupKey, err)
+		return
+	}
+
+	message, changed := m.record(groupKey, version, entries)
+	if !changed {
+		return
+	}
+
+	for _, client := range m.groupClients(groupKey) {
+		m.hub.trySend(client, message)
+	}
+}
+
+// record stores the latest entries for a group and reports whether they changed
+// Returns the group's current encoded message, shared by every client in the group
+func (m *subscriptionManager) record(groupKey string, version int64, entries []models.LeaderboardEntry) (*frame, bool) {
+	encodedEntries := encodeMessage(entries)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[groupKey]
+	if !ok {
+		return nil, false
+	}
+	if bytes.Equal(group.lastEntries, encodedEntries) {
+		return group.lastMessage, false
+	}
+
+	group.lastEntries = encodedEntries
+	group.lastMessage = newFrame("SUBSCRIPTION_UPDATE", version, SubscriptionUpdate{
+		Type:         "SUBSCRIPTION_UPDATE",
+		Subscription: group.spec.key(),
+		Version:      version,
+		Entries:      entries,
+	})
+	return group.lastMessage, true
+}
+
+// resolve fetches the current entries for a subscription
+func (m *subscriptionManager) resolve(ctx context.Context, spec subscriptionSpec) ([]models.LeaderboardEntry, error) {
+	switch spec.kind {
+	case SubscriptionRange:
+		page, err := m.reader.GetLeaderboardFor(ctx, spec.viewer, spec.start-1, spec.end-spec.start+1)
+		if err != nil {
+			return nil, err
+		}
+		return page.Data, nil
+
+	case SubscriptionUser:
+		user, err := m.reader.SearchUserFor(ctx, spec.viewer, spec.username)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			// Unknown users resolve to no entries until they post a score
+			return []models.LeaderboardEntry{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.LeaderboardEntry{{
+			Rank:     user.GlobalRank,
+			Username: user.Username,
+			Rating:   user.Rating,
+		}}, nil
+
+	default:
+		user, err := m.reader.SearchUserFor(ctx, spec.viewer, spec.username)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return []models.LeaderboardEntry{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset := user.GlobalRank - 1 - spec.radius
+		if offset < 0 {
+			offset = 0
+		}
+		page, err := m.reader.GetLeaderboardFor(ctx, spec.viewer, offset, 2*spec.radius+1)
+		if err != nil {
+			return nil, err
+		}
+		return page.Data, nil
+	}
+}
+
+// encodeMessage encodes a hub message to JSON, logging (rare) failures
+func encodeMessage(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("❌ Failed to marshal hub message: %v", err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,42 @@
+package websocket
+
+import "context"
+
+// WaitForVersion blocks until the hub's version differs from version or ctx is done,
+// and returns the latest version. It is driven by the hub's change notifications, so
+// waiting costs no Redis calls. A version that moved backwards (Redis was reseeded)
+// also counts as a change, so stale clients are told to refetch.
+func (h *Hub) WaitForVersion(ctx context.Context, version int64) (int64, error) {
+	h.metrics.waiters.Add(1)
+	defer h.metrics.waiters.Add(-1)
+
+	for {
+		// Take the channel before reading the version so a change in between still wakes us
+		wait := h.versionWaiter()
+
+		if current := h.lastVersion.Load(); current != 0 && current != version {
+			return current, nil
+		}
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return h.lastVersion.Load(), ctx.Err()
+		}
+	}
+}
+
+// versionWaiter returns a channel closed on the next version change
+func (h *Hub) versionWaiter() <-chan struct{} {
+	h.waitMu.Lock()
+	defer h.waitMu.Unlock()
+	return h.versionWait
+}
+
+// wakeWaiters releases every WaitForVersion call blocked on the current version
+func (h *Hub) wakeWaiters() {
+	h.waitMu.Lock()
+	defer h.waitMu.Unlock()
+	close(h.versionWait)
+	h.versionWait = make(chan struct{})
+}
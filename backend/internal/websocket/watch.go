@@ -0,0 +1,353 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"backend/internal/models"
+)
+
+const (
+	// Maximum number of users a single client may watch
+	maxWatchesPerClient = 10
+
+	// Largest top-N boundary a watch may track
+	maxWatchTopN = 1000
+)
+
+// Reasons carried by RANK_CHANGED
+const (
+	RankUp        = "RANK_UP"        // The user's own update moved them up
+	RankDown      = "RANK_DOWN"      // The user's own update moved them down
+	Overtaken     = "OVERTAKEN"      // Others passed the user without the user's rating changing
+	OthersDropped = "OTHERS_DROPPED" // The user moved up without their rating changing
+	EnteredTopN   = "ENTERED_TOP_N"  // The user crossed into the watched top N
+	LeftTopN      = "LEFT_TOP_N"     // The user dropped out of the watched top N
+)
+
+// RankChanged notifies a watcher that a user's rank changed
+// A rank of 0 means the user is not on the leaderboard
+type RankChanged struct {
+	Type     string   `json:"type"`
+	Username string   `json:"username"`
+	OldRank  int      `json:"old_rank"`
+	NewRank  int      `json:"new_rank"`
+	Rating   int      `json:"rating"`
+	TopN     int      `json:"top_n,omitempty"`
+	Reasons  []string `json:"reasons"`
+	Version  int64    `json:"version"`
+}
+
+// watchTarget identifies a watched user as seen by the watchers sharing it
+// self marks a shadow-banned player watching themselves: only they still see their rank
+type watchTarget struct {
+	username string
+	self     bool
+}
+
+// viewer returns the viewer the target's rank is read for
+func (t watchTarget) viewer() string {
+	if t.self {
+		return t.username
+	}
+	return ""
+}
+
+// watchedUser is the shared state of every client watching the same target
+type watchedUser struct {
+	clients map[*Client]int // Client -> top N it watches (0 = rank changes only)
+	entry   models.LeaderboardEntry
+	known   bool // entry holds a resolved baseline
+}
+
+// watchManager tracks per-user watches and sends RANK_CHANGED when a watched rank moves
+// Like subscriptions it runs its own goroutine, evaluating once per signalled version
+type watchManager struct {
+	hub    *Hub
+	reader LeaderboardReader
+
+	mu       sync.Mutex
+	users    map[watchTarget]*watchedUser
+	byClient map[*Client]map[string]watchTarget // Client -> watched username -> target
+
+	// Latest version to evaluate; buffered so bursts collapse into one pass
+	versions chan int64
+}
+
+// newWatchManager creates an empty watch manager
+func newWatchManager(hub *Hub, reader LeaderboardReader) *watchManager {
+	return &watchManager{
+		hub:      hub,
+		reader:   reader,
+		users:    make(map[watchTarget]*watchedUser),
+		byClient: make(map[*Client]map[string]watchTarget),
+		versions: make(chan int64, 1),
+	}
+}
+
+// run evaluates watches whenever a new version is signalled
+func (m *watchManager) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case version := <-m.versions:
+			m.evaluate(ctx, version)
+		}
+	}
+}
+
+// notify signals a new version, replacing any version still waiting to be evaluated
+func (m *watchManager) notify(version int64) {
+	notifyLatest(m.versions, version)
+}
+
+// watch registers a client for rank changes of username
+func (m *watchManager) watch(client *Client, username string, topN int) error {
+	if username == "" {
+		return fmt.Errorf("watch requires a username")
+	}
+	if topN < 0 || topN > maxWatchTopN {
+		return fmt.Errorf("top_n must be between 0 and %d", maxWatchTopN)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	target := watchTarget{username: username}
+	if username == client.opts.Player {
+		target.self = m.hub.viewer(ctx, client) != ""
+	}
+
+	m.mu.Lock()
+	watches := m.byClient[client]
+	previous, watching := watches[username]
+	if !watching && len(watches) >= maxWatchesPerClient {
+		m.mu.Unlock()
+		return fmt.Errorf("at most %d watches per connection", maxWatchesPerClient)
+	}
+	if watching && previous != target {
+		m.removeWatcherLocked(client, previous)
+	}
+	if watches == nil {
+		watches = make(map[string]watchTarget)
+		m.byClient[client] = watches
+	}
+	watches[username] = target
+
+	user, exists := m.users[target]
+	if !exists {
+		user = &watchedUser{clients: make(map[*Client]int)}
+		m.users[target] = user
+	}
+	// Watching again updates the top N
+	user.clients[client] = topN
+	needsBaseline := !user.known
+	m.mu.Unlock()
+
+	m.hub.trySend(client, controlFrame("WATCHING", watchKey(username), ""))
+
+	if !needsBaseline {
+		return nil
+	}
+
+	// First watcher: record the current rank so the next change has something to compare to
+	entries, err := m.reader.GetUserEntriesFor(ctx, target.viewer(), []string{username})
+	if err != nil {
+		return fmt.Errorf("failed to resolve rank of %s: %w", username, err)
+	}
+
+	m.mu.Lock()
+	if user, ok := m.users[target]; ok && !user.known {
+		user.entry = entries[username]
+		user.known = true
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// unwatch removes a client's watch on username
+func (m *watchManager) unwatch(client *Client, username string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target, ok := m.byClient[client][username]
+	if !ok {
+		return false
+	}
+	delete(m.byClient[client], username)
+	m.removeWatcherLocked(client, target)
+	return true
+}
+
+// removeClient drops every watch held by a disconnected client
+func (m *watchManager) removeClient(client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, target := range m.byClient[client] {
+		m.removeWatcherLocked(client, target)
+	}
+	delete(m.byClient, client)
+}
+
+// removeWatcherLocked removes a client from a watched target, forgetting it when unwatched
+func (m *watchManager) removeWatcherLocked(client *Client, target watchTarget) {
+	user, ok := m.users[target]
+	if !ok {
+		return
+	}
+	delete(user.clients, client)
+	if len(user.clients) == 0 {
+		delete(m.users, target)
+	}
+}
+
+// rankNotification is a RANK_CHANGED queued for one client
+type rankNotification struct {
+	client  *Client
+	message *frame
+}
+
+// evaluate fetches the ranks of every watched user in one batch (plus one read per
+// shadow-banned player watching themselves) and notifies the watchers of each user whose
+// rank moved
+func (m *watchManager) evaluate(ctx context.Context, version int64) {
+	m.mu.Lock()
+	targets := make([]watchTarget, 0, len(m.users))
+	var usernames []string
+	for target := range m.users {
+		targets = append(targets, target)
+		if !target.self {
+			usernames = append(usernames, target.username)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	resolveCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	entries, err := m.resolve(resolveCtx, targets, usernames)
+	cancel()
+	if err != nil {
+		log.Printf("⚠️ Failed to resolve watched ranks: %v", err)
+		return
+	}
+
+	var notifications []rankNotification
+
+	m.mu.Lock()
+	for _, target := range targets {
+		username := target.username
+		user, ok := m.users[target]
+		if !ok {
+			continue // Unwatched while resolving
+		}
+
+		current := entries[target] // Zero value when not on the leaderboard
+		previous := user.entry
+		user.entry = current
+		if !user.known {
+			user.known = true
+			continue
+		}
+		if current.Rank == previous.Rank {
+			continue
+		}
+
+		// Clients watching the same user with the same top N share one frame
+		frames := make(map[int]*frame)
+		for client, topN := range user.clients {
+			message, ok := frames[topN]
+			if !ok {
+				message = newFrame("RANK_CHANGED", version, RankChanged{
+					Type:     "RANK_CHANGED",
+					Username: username,
+					OldRank:  previous.Rank,
+					NewRank:  current.Rank,
+					Rating:   current.Rating,
+					TopN:     topN,
+					Reasons:  rankChangeReasons(previous, current, topN),
+					Version:  version,
+				})
+				frames[topN] = message
+			}
+			notifications = append(notifications, rankNotification{client: client, message: message})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, n := range notifications {
+		m.hub.trySend(n.client, n.message)
+	}
+}
+
+// resolve fetches the entries of every target: usernames (the public targets) in one
+// batch, then each shadow-banned player watching themselves
+func (m *watchManager) resolve(ctx context.Context, targets []watchTarget, usernames []string) (map[watchTarget]models.LeaderboardEntry, error) {
+	resolved := make(map[watchTarget]models.LeaderboardEntry, len(targets))
+
+	if len(usernames) > 0 {
+		entries, err := m.reader.GetUserEntriesFor(ctx, "", usernames)
+		if err != nil {
+			return nil, err
+		}
+		for username, entry := range entries {
+			resolved[watchTarget{username: username}] = entry
+		}
+	}
+
+	for _, target := range targets {
+		if !target.self {
+			continue
+		}
+		entries, err := m.reader.GetUserEntriesFor(ctx, target.viewer(), []string{target.username})
+		if err != nil {
+			return nil, err
+		}
+		if entry, ok := entries[target.username]; ok {
+			resolved[target] = entry
+		}
+	}
+	return resolved, nil
+}
+
+// rankChangeReasons classifies a rank transition
+// Rank 0 (not on the leaderboard) ranks below everyone
+func rankChangeReasons(previous, current models.LeaderboardEntry, topN int) []string {
+	oldRank, newRank := previous.Rank, current.Rank
+	improved := newRank != 0 && (oldRank == 0 || newRank < oldRank)
+	ownUpdate := previous.Rating != current.Rating || oldRank == 0 || newRank == 0
+
+	var reasons []string
+	switch {
+	case improved && ownUpdate:
+		reasons = append(reasons, RankUp)
+	case improved:
+		reasons = append(reasons, OthersDropped)
+	case ownUpdate:
+		reasons = append(reasons, RankDown)
+	default:
+		reasons = append(reasons, Overtaken)
+	}
+
+	if topN > 0 {
+		wasIn := oldRank != 0 && oldRank <= topN
+		isIn := newRank != 0 && newRank <= topN
+		if !wasIn && isIn {
+			reasons = append(reasons, EnteredTopN)
+		} else if wasIn && !isIn {
+			reasons = append(reasons, LeftTopN)
+		}
+	}
+	return reasons
+}
+
+// watchKey names a watch in acknowledgements
+func watchKey(username string) string {
+	return "watch:" + username
+}
@@ -14,6 +14,7 @@ import (
 type ScoreUpdateTask struct {
 	Username string
 	Rating   int
+	APIKeyID uint // Key that submitted the update, recorded in score history (0 for internal writes)
 }
 
 // WorkerPool manages a pool of workers for asynchronous database writes
@@ -103,7 +104,7 @@ func (wp *WorkerPool) processTask(workerID int, task ScoreUpdateTask) {
 	defer cancel()
 	
 	// Perform the database upsert
-	err := wp.postgresRepo.UpsertUser(ctx, task.Username, task.Rating)
+	err := wp.postgresRepo.UpsertUser(ctx, task.Username, task.Rating, task.APIKeyID)
 	
 	processingTime := time.Since(startTime)
 	
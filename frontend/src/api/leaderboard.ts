@@ -21,12 +21,16 @@ const getBaseURL = () => {
   }
 };
 
+// API key sent with every request; score updates need the write-scores scope
+const API_KEY = process.env.EXPO_PUBLIC_API_KEY;
+
 // Create axios instance
 export const apiClient = axios.create({
   baseURL: getBaseURL(),
   timeout: 10000,
   headers: {
     'Content-Type': 'application/json',
+    ...(API_KEY ? { 'X-API-Key': API_KEY } : {}),
   },
 });
 